@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/library-data-platform/ldpmarc/marc"
 )
@@ -21,6 +22,37 @@ var srsRecordsFlag = flag.String("r", "", "Name of table containing SRS records
 var srsMarcFlag = flag.String("m", "", "Name of table containing SRS MARC (JSON) data to read")
 var srsMarcAttrFlag = flag.String("j", "", "Name of column containing MARC JSON data")
 var metadbFlag = flag.Bool("M", false, "Metadb compatibility")
+var validateFlag = flag.Bool("validate", false, "Validate records and write findings to marctab.validation")
+var validateReportFlag = flag.String("validate-report", "", "Write validation findings to CSV file instead of a table")
+var validateSeverityFlag = flag.String("validate-severity", "error,warning",
+	"Validation severities to report (error, warning, info)")
+var holdingsFlag = flag.Bool("holdings", false, "Also transform MARC holdings records")
+var authorityFlag = flag.Bool("authority", false, "Also transform MARC authority records")
+var statesFlag = flag.String("states", "", "Record states to transform, e.g. ACTUAL,OLD (default ACTUAL)")
+var recordTypesFlag = flag.String("record-types", "", "Record types to transform into the bibliographic table (default MARC_BIB)")
+var includeNoIDFlag = flag.Bool("include-no-id", false, "Transform records that have no identifier in 999$i")
+var decode007Flag = flag.Bool("decode-007", false, "Decode 006 and 007 fields into a separate table")
+var nfcFlag = flag.Bool("nfc", false, "Normalize content to Unicode NFC")
+var contentFoldedFlag = flag.Bool("content-folded", false, "Add content_folded column for matching")
+var summaryFlag = flag.Bool("summary", false, "Write main bibliographic elements of each record to a summary table")
+var namesFlag = flag.Bool("names", false, "Write names in 1XX and 7XX fields with their roles to a names table")
+var leader008Flag = flag.Bool("leader-008", false, "Decode the leader and 008 into a leader_008 table")
+var altGraphicFlag = flag.Bool("alt-graphic", false, "Write links between 880 fields and the fields they parallel to an 880 table")
+var identifiersFlag = flag.Bool("identifiers", false, "Write normalized ISBN, ISSN, LCCN, and OCLC numbers to an identifiers table")
+var callNumbersFlag = flag.Bool("call-numbers", false, "Parse LC and Dewey call numbers into a call_numbers table with sort keys")
+var subjectsFlag = flag.Bool("subjects", false, "Assemble 6XX subject headings into a subjects table")
+var datesFlag = flag.Bool("dates", false, "Write normalized publication years to a dates table")
+var languagesFlag = flag.Bool("languages", false, "Write language codes in 008 and 041 to a languages table")
+var fieldLinksFlag = flag.Bool("field-links", false, "Write field links and sequence numbers in $8 to a field_link table")
+var mappingFlag = flag.String("mapping", "", "Write a wide table with columns defined by mapping file (YAML or JSON)")
+var mappingCSVFlag = flag.String("mapping-csv", "", "Write the wide table defined by -mapping to CSV file instead of a table")
+var linksFlag = flag.Bool("links", false, "Write URLs in 856 to a links table")
+var linkHostsFlag = flag.String("link-hosts", "", "Check links in 856 against allowed and denied hosts listed in file")
+var formatsFlag = flag.Bool("formats", false, "Classify the format of each record into a format table")
+var formatRulesFlag = flag.String("format-rules", "", "Classify formats using rules from file instead of the embedded rules")
+var lenientFlag = flag.Bool("lenient", false, "Repair malformed fields instead of skipping records, and write repairs to a diagnostics table")
+var fieldCksumFlag = flag.Bool("field-cksum", false, "Rewrite only changed fields of changed records in incremental updates")
+var cksumIgnoreFlag = flag.String("cksum-ignore", "", "Tag patterns to exclude from change detection in incremental updates, e.g. 005,9XX")
 var helpFlag = flag.Bool("h", false, "Help for ldpmarc")
 
 //var tableoutSchema = "marctab"
@@ -34,6 +66,10 @@ var helpFlag = flag.Bool("h", false, "Help for ldpmarc")
 var program = "ldpmarc"
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "profile" {
+		profileMain(os.Args[2:])
+		return
+	}
 	flag.Parse()
 	if len(flag.Args()) > 0 {
 		printerr("invalid argument: %s", flag.Arg(0))
@@ -65,18 +101,48 @@ func main() {
 		verbose = 2
 	}
 	opt := &marc.TransformOptions{
-		FullUpdate:   *fullUpdateFlag,
-		Datadir:      *datadirFlag,
-		Users:        users,
-		TrigramIndex: *trigramIndexFlag,
-		NoIndexes:    *noIndexesFlag,
-		Verbose:      verbose,
-		CSVFileName:  *csvFilenameFlag,
-		SRSRecords:   *srsRecordsFlag,
-		SRSMarc:      *srsMarcFlag,
-		SRSMarcAttr:  *srsMarcAttrFlag,
-		Metadb:       *metadbFlag,
-		PrintErr:     printerr,
+		FullUpdate:       *fullUpdateFlag,
+		Datadir:          *datadirFlag,
+		Users:            users,
+		TrigramIndex:     *trigramIndexFlag,
+		NoIndexes:        *noIndexesFlag,
+		Verbose:          verbose,
+		CSVFileName:      *csvFilenameFlag,
+		SRSRecords:       *srsRecordsFlag,
+		SRSMarc:          *srsMarcFlag,
+		SRSMarcAttr:      *srsMarcAttrFlag,
+		Metadb:           *metadbFlag,
+		PrintErr:         printerr,
+		Validate:         *validateFlag,
+		ValidateReport:   *validateReportFlag,
+		ValidateSeverity: *validateSeverityFlag,
+		Holdings:         *holdingsFlag,
+		Authority:        *authorityFlag,
+		States:           splitList(*statesFlag),
+		RecordTypes:      splitList(*recordTypesFlag),
+		IncludeNoID:      *includeNoIDFlag,
+		Decode007:        *decode007Flag,
+		NFC:              *nfcFlag,
+		ContentFolded:    *contentFoldedFlag,
+		Summary:          *summaryFlag,
+		Names:            *namesFlag,
+		Leader008:        *leader008Flag,
+		AltGraphic:       *altGraphicFlag,
+		Identifiers:      *identifiersFlag,
+		CallNumbers:      *callNumbersFlag,
+		Subjects:         *subjectsFlag,
+		Dates:            *datesFlag,
+		Languages:        *languagesFlag,
+		FieldLinks:       *fieldLinksFlag,
+		Mapping:          *mappingFlag,
+		MappingCSV:       *mappingCSVFlag,
+		Links:            *linksFlag,
+		LinkHosts:        *linkHostsFlag,
+		Formats:          *formatsFlag,
+		FormatRules:      *formatRulesFlag,
+		Lenient:          *lenientFlag,
+		FieldCksum:       *fieldCksumFlag,
+		CksumIgnore:      splitList(*cksumIgnoreFlag),
 	}
 	if err := marc.Run(opt); err != nil {
 		printerr("%s", err)
@@ -84,6 +150,16 @@ func main() {
 	}
 }
 
+func splitList(s string) []string {
+	list := make([]string, 0)
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
 func printerr(format string, v ...any) {
 	_, _ = fmt.Fprintf(os.Stderr, "%s: %s\n", program, fmt.Sprintf(format, v...))
 }
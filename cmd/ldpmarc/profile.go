@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/library-data-platform/ldpmarc/marc"
+)
+
+func profileMain(args []string) {
+	fs := flag.NewFlagSet("profile", flag.ExitOnError)
+	datadir := fs.String("D", "", "Data directory")
+	metadb := fs.Bool("M", false, "Metadb compatibility")
+	srsRecords := fs.String("r", "", "Name of table containing SRS records to read")
+	srsMarc := fs.String("m", "", "Name of table containing SRS MARC (JSON) data to read")
+	srsMarcAttr := fs.String("j", "", "Name of column containing MARC JSON data")
+	file := fs.String("file", "", "Read SRS MARC (JSON) records from file, one per line, instead of a database")
+	csvFilename := fs.String("c", "", "Write output to CSV file instead of a summary table")
+	tags := fs.String("tags", "", "Comma-separated tag patterns to profile, e.g. 9XX,590")
+	samples := fs.Int("samples", 3, "Number of sample values to report")
+	verbose := fs.Bool("v", false, "Enable verbose output")
+	help := fs.Bool("h", false, "Help for ldpmarc profile")
+	_ = fs.Parse(args)
+	if fs.NArg() > 0 {
+		printerr("invalid argument: %s", fs.Arg(0))
+		os.Exit(2)
+	}
+	if *help || (*datadir == "" && *file == "") {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage of %s profile:\n", program)
+		fs.PrintDefaults()
+		if *help {
+			return
+		} else {
+			os.Exit(2)
+		}
+	}
+	v := 1
+	if *verbose {
+		v = 2
+	}
+	opt := &marc.ProfileOptions{
+		Datadir:     *datadir,
+		Metadb:      *metadb,
+		SRSRecords:  *srsRecords,
+		SRSMarc:     *srsMarc,
+		SRSMarcAttr: *srsMarcAttr,
+		FileName:    *file,
+		CSVFileName: *csvFilename,
+		Tags:        splitList(*tags),
+		Samples:     *samples,
+		Verbose:     v,
+		PrintErr:    printerr,
+	}
+	if err := marc.Profile(opt); err != nil {
+		printerr("%s", err)
+		os.Exit(1)
+	}
+}
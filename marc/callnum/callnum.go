@@ -0,0 +1,183 @@
+// Package callnum parses LC and Dewey call numbers into their components and
+// generates sort keys in shelf order.
+package callnum
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+// Classification schemes.
+const (
+	LC    = "lc"
+	Dewey = "dewey"
+)
+
+// CallNumber is a call number found in a record.
+type CallNumber struct {
+	Field string
+	Ord   int16
+	// Scheme is LC or Dewey.
+	Scheme string
+	// Raw is the call number as it appears in the record, with the
+	// classification and item parts separated by a blank.
+	Raw string
+	// Class is the LC class letters or the Dewey main class (three
+	// digits).
+	Class string
+	// Number is the LC class number or the Dewey decimal number,
+	// including any decimal fraction.
+	Number string
+	// Cutters lists the Cutter numbers, e.g. "A52".
+	Cutters []string
+	// Year is a year following the Cutter numbers.
+	Year string
+	// Rest is any remaining part of the call number.
+	Rest string
+	// SortKey orders call numbers of the same scheme in shelf order.
+	SortKey string
+	// Parsed reports whether the call number could be parsed.
+	Parsed bool
+}
+
+// Extract returns the call numbers in 050, 090, and 099 (LC) and 082 and
+// 092 (Dewey) of a transformed record.  For each field, the first $a and $b
+// are used.
+func Extract(mrecs []srs.Marc) []CallNumber {
+	var cns []CallNumber
+	for _, sfs := range srs.Fields(mrecs) {
+		m := sfs[0]
+		var scheme string
+		switch m.Field {
+		case "050", "090", "099":
+			scheme = LC
+		case "082", "092":
+			scheme = Dewey
+		}
+		var a, b string
+		for _, sf := range sfs {
+			switch {
+			case sf.SF == "a" && a == "":
+				a = sf.Content
+			case sf.SF == "b" && b == "":
+				b = sf.Content
+			}
+		}
+		if scheme == "" || strings.TrimSpace(a) == "" {
+			continue
+		}
+		raw := strings.TrimSpace(a)
+		if b = strings.TrimSpace(b); b != "" {
+			raw += " " + b
+		}
+		var cn CallNumber
+		if scheme == LC {
+			cn = ParseLC(raw)
+		} else {
+			cn = ParseDewey(raw)
+		}
+		cn.Field = m.Field
+		cn.Ord = m.Ord
+		cns = append(cns, cn)
+	}
+	return cns
+}
+
+var lcPattern = regexp.MustCompile(`^([A-Z]{1,3}) ?(\d{1,4}(?:\.\d+)?)((?: ?\.? ?[A-Z]\d+[a-z]*){0,3})(?: (\d{4}[a-z]?))?(?: (.*))?$`)
+
+var cutterPattern = regexp.MustCompile(`[A-Z]\d+[a-z]*`)
+
+// ParseLC parses an LC call number such as "QA76.73.J38 S58 2005".
+func ParseLC(raw string) CallNumber {
+	cn := CallNumber{Scheme: LC, Raw: raw}
+	s := normalizeSpace(raw)
+	n := lcClassEnd(s)
+	m := lcPattern.FindStringSubmatch(strings.ToUpper(s[:n]) + s[n:])
+	if m == nil {
+		return cn
+	}
+	cn.Class = m[1]
+	cn.Number = m[2]
+	cn.Cutters = cutterPattern.FindAllString(m[3], -1)
+	cn.Year = m[4]
+	cn.Rest = m[5]
+	cn.Parsed = true
+	// The class letters are padded so that shorter classes sort first,
+	// and the integer part of the class number is padded with zeros.
+	// Cutter numbers are decimal fractions and sort as strings.
+	whole, frac, _ := strings.Cut(cn.Number, ".")
+	key := padRight(cn.Class, 3) + " " + strings.Repeat("0", 4-len(whole)) + whole
+	if frac != "" {
+		key += "." + frac
+	}
+	for _, c := range cn.Cutters {
+		key += " " + strings.ToUpper(c)
+	}
+	if cn.Year != "" {
+		key += " " + cn.Year
+	}
+	if cn.Rest != "" {
+		key += " " + strings.ToUpper(cn.Rest)
+	}
+	cn.SortKey = key
+	return cn
+}
+
+// lcClassEnd returns the length of the leading class letters in s, so that
+// lowercase class letters can be accepted.
+func lcClassEnd(s string) int {
+	i := 0
+	for i < len(s) && (s[i] >= 'A' && s[i] <= 'Z' || s[i] >= 'a' && s[i] <= 'z') {
+		i++
+	}
+	return i
+}
+
+var deweyPattern = regexp.MustCompile(`^(\d{3})(?:\.(\d+))?(?: ?\.?([A-Z]\d+[a-z]*))?(?: (\d{4}[a-z]?))?(?: (.*))?$`)
+
+// ParseDewey parses a Dewey call number such as "641.5/972 B123 2005".
+// Segmentation marks ("/") and prime marks ("'") are ignored.
+func ParseDewey(raw string) CallNumber {
+	cn := CallNumber{Scheme: Dewey, Raw: raw}
+	s := strings.NewReplacer("/", "", "'", "").Replace(raw)
+	m := deweyPattern.FindStringSubmatch(normalizeSpace(s))
+	if m == nil {
+		return cn
+	}
+	cn.Class = m[1]
+	cn.Number = m[1]
+	if m[2] != "" {
+		cn.Number += "." + m[2]
+	}
+	if m[3] != "" {
+		cn.Cutters = []string{m[3]}
+	}
+	cn.Year = m[4]
+	cn.Rest = m[5]
+	cn.Parsed = true
+	key := cn.Number
+	for _, c := range cn.Cutters {
+		key += " " + strings.ToUpper(c)
+	}
+	if cn.Year != "" {
+		key += " " + cn.Year
+	}
+	if cn.Rest != "" {
+		key += " " + strings.ToUpper(cn.Rest)
+	}
+	cn.SortKey = key
+	return cn
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func padRight(s string, n int) string {
+	if len(s) >= n {
+		return s
+	}
+	return s + strings.Repeat(" ", n-len(s))
+}
@@ -0,0 +1,114 @@
+package callnum
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseLC(t *testing.T) {
+	tests := []struct {
+		raw                       string
+		class, number, year, rest string
+		cutters                   []string
+		parsed                    bool
+	}{
+		{"QA76.73.J38 S58 2005", "QA", "76.73", "2005", "", []string{"J38", "S58"}, true},
+		{"QA76.73 .J38 2005", "QA", "76.73", "2005", "", []string{"J38"}, true},
+		{"qa76.73.J38", "QA", "76.73", "", "", []string{"J38"}, true},
+		{"PS3545.I345 Z5 1985 v. 2", "PS", "3545", "1985", "v. 2", []string{"I345", "Z5"}, true},
+		{"KF 4550 .A2", "KF", "4550", "", "", []string{"A2"}, true},
+		{"E184.A1 2005b", "E", "184", "2005b", "", []string{"A1"}, true},
+		{"Microfilm 1234", "", "", "", "", nil, false},
+		{"12345", "", "", "", "", nil, false},
+	}
+	for _, tt := range tests {
+		cn := ParseLC(tt.raw)
+		if cn.Parsed != tt.parsed || cn.Class != tt.class || cn.Number != tt.number || cn.Year != tt.year ||
+			cn.Rest != tt.rest || !reflect.DeepEqual(cn.Cutters, tt.cutters) {
+			t.Errorf("ParseLC(%q) = %+v", tt.raw, cn)
+		}
+		if cn.Parsed == (cn.SortKey == "") {
+			t.Errorf("ParseLC(%q): parsed %v with sort key %q", tt.raw, cn.Parsed, cn.SortKey)
+		}
+	}
+}
+
+func TestParseDewey(t *testing.T) {
+	tests := []struct {
+		raw                       string
+		class, number, year, rest string
+		cutters                   []string
+		parsed                    bool
+	}{
+		{"641.5/972 B123 2005", "641", "641.5972", "2005", "", []string{"B123"}, true},
+		{"813'.54 K58s", "813", "813.54", "", "", []string{"K58s"}, true},
+		{"020", "020", "020", "", "", nil, true},
+		{"005.133 .B23 1999 v.1", "005", "005.133", "1999", "v.1", []string{"B23"}, true},
+		{"Fic SMI", "", "", "", "", nil, false},
+		{"64.5", "", "", "", "", nil, false},
+	}
+	for _, tt := range tests {
+		cn := ParseDewey(tt.raw)
+		if cn.Parsed != tt.parsed || cn.Class != tt.class || cn.Number != tt.number || cn.Year != tt.year ||
+			cn.Rest != tt.rest || !reflect.DeepEqual(cn.Cutters, tt.cutters) {
+			t.Errorf("ParseDewey(%q) = %+v", tt.raw, cn)
+		}
+	}
+}
+
+// sortKeys sorts call numbers by their sort keys and returns them in that
+// order.
+func sortKeys(parse func(string) CallNumber, raws []string) []string {
+	cns := make([]CallNumber, len(raws))
+	for i, r := range raws {
+		cns[i] = parse(r)
+	}
+	sort.SliceStable(cns, func(i, j int) bool { return cns[i].SortKey < cns[j].SortKey })
+	sorted := make([]string, len(cns))
+	for i, cn := range cns {
+		sorted[i] = cn.Raw
+	}
+	return sorted
+}
+
+func TestLCSortKey(t *testing.T) {
+	// In shelf order.  Shorter class letters file first, class numbers
+	// file numerically, and Cutter numbers file as decimal fractions.
+	want := []string{
+		"A1 .B2",
+		"AB12 .C3",
+		"B2 .A1",
+		"QA9 .A1",
+		"QA76 .A1",
+		"QA76.73.J38 2005",
+		"QA76.73.J38 S58 2005",
+		"QA76.73.J385",
+		"QA76.73.J4",
+		"QA76.8 .A1",
+		"QA760 .A1",
+		"QB1 .A1",
+	}
+	raws := []string{want[9], want[4], want[11], want[7], want[0], want[5], want[10], want[2], want[8],
+		want[3], want[6], want[1]}
+	if got := sortKeys(ParseLC, raws); !reflect.DeepEqual(got, want) {
+		t.Errorf("LC shelf order = %q, want %q", got, want)
+	}
+}
+
+func TestDeweySortKey(t *testing.T) {
+	want := []string{
+		"005.133 B23",
+		"005.133 B3",
+		"020 A1",
+		"641.5 A1",
+		"641.59 C1",
+		"641.5972 B123",
+		"641.5972 B123 2005",
+		"813.54 K58s",
+	}
+	raws := []string{want[7], want[3], want[5], want[0], want[6], want[2], want[4], want[1]}
+	if got := sortKeys(ParseDewey, raws); !reflect.DeepEqual(got, want) {
+		t.Errorf("Dewey shelf order = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+// Package contributor extracts personal, corporate, and meeting names from
+// the 1XX and 7XX fields of transformed SRS MARC records, with their roles.
+package contributor
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/textnorm"
+)
+
+// Name types.
+const (
+	Personal  = "personal"
+	Corporate = "corporate"
+	Meeting   = "meeting"
+)
+
+// Contributor is a name found in a 100, 110, 111, 700, 710, or 711 field.
+type Contributor struct {
+	Field string
+	Ord   int16
+	// Type is Personal, Corporate, or Meeting.
+	Type string
+	// Main reports whether the name is the main entry (1XX) rather than
+	// an added entry (7XX).
+	Main bool
+	// Name is the name without dates, relators, or title.
+	Name string
+	// Dates is $d, without any enclosing parentheses.
+	Dates string
+	// Roles lists the relator terms ($e, or $j in X11 fields) and the
+	// labels of relator codes ($4), in order and without duplicates.
+	Roles []string
+	// RelatorCodes lists the relator codes in $4.
+	RelatorCodes []string
+	// AuthorityURIs lists $0.
+	AuthorityURIs []string
+}
+
+type tagInfo struct {
+	nameType string
+	// subfields lists the codes of subfields that are part of the name.
+	subfields string
+	// relator is the code of the relator term subfield.
+	relator string
+}
+
+var tags = map[string]tagInfo{
+	"100": {Personal, "abcq", "e"},
+	"110": {Corporate, "abcn", "e"},
+	"111": {Meeting, "acenq", "j"},
+	"700": {Personal, "abcq", "e"},
+	"710": {Corporate, "abcn", "e"},
+	"711": {Meeting, "acenq", "j"},
+}
+
+//go:embed relators.txt
+var relatorsText string
+
+var relators = parseRelators(relatorsText)
+
+func parseRelators(text string) map[string]string {
+	m := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, label, ok := strings.Cut(line, " ")
+		if !ok || len(code) != 3 {
+			panic("invalid relator: " + line)
+		}
+		m[code] = strings.TrimSpace(label)
+	}
+	return m
+}
+
+// relatorURIPrefixes are removed from relator codes given as URIs.
+var relatorURIPrefixes = []string{
+	"http://id.loc.gov/vocabulary/relators/",
+	"https://id.loc.gov/vocabulary/relators/",
+}
+
+// relatorCode returns a relator code, which may be given as a URI, in
+// lowercase and without a URI prefix or final period.
+func relatorCode(code string) string {
+	code = strings.TrimSpace(code)
+	for _, p := range relatorURIPrefixes {
+		code = strings.TrimPrefix(code, p)
+	}
+	return strings.ToLower(strings.TrimRight(code, "."))
+}
+
+// Relator returns the label of a relator code, which may be given as a URI.
+// It reports false if the code is not defined.
+func Relator(code string) (string, bool) {
+	label, ok := relators[relatorCode(code)]
+	return label, ok
+}
+
+// Extract returns the names in the 100, 110, 111, 700, 710, and 711 fields of
+// a transformed record.
+func Extract(mrecs []srs.Marc) []Contributor {
+	var cs []Contributor
+	for _, sfs := range srs.Fields(mrecs) {
+		m := sfs[0]
+		info, ok := tags[m.Field]
+		if !ok {
+			continue
+		}
+		c := Contributor{Field: m.Field, Ord: m.Ord, Type: info.nameType, Main: m.Field[0] == '1'}
+		var name []string
+		for _, sf := range sfs {
+			v := strings.TrimSpace(sf.Content)
+			if v == "" {
+				continue
+			}
+			switch {
+			case sf.SF == "d":
+				if c.Dates == "" {
+					c.Dates = strings.TrimRight(strings.TrimLeft(textnorm.TrimISBD(v), "("), ")")
+				}
+			case sf.SF == info.relator:
+				c.addRole(textnorm.TrimISBD(v))
+			case sf.SF == "4":
+				code := relatorCode(v)
+				c.RelatorCodes = append(c.RelatorCodes, code)
+				if label, ok := Relator(code); ok {
+					c.addRole(label)
+				}
+			case sf.SF == "0":
+				c.AuthorityURIs = append(c.AuthorityURIs, v)
+			case sf.SF != "" && strings.Contains(info.subfields, sf.SF):
+				name = append(name, v)
+			}
+		}
+		c.Name = textnorm.TrimISBD(strings.Join(name, " "))
+		if c.Name == "" {
+			continue
+		}
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+// addRole adds a role if it is not already listed.
+func (c *Contributor) addRole(role string) {
+	if role == "" {
+		return
+	}
+	for _, r := range c.Roles {
+		if strings.EqualFold(r, role) {
+			return
+		}
+	}
+	c.Roles = append(c.Roles, role)
+}
@@ -0,0 +1,62 @@
+package contributor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+func TestRelator(t *testing.T) {
+	tests := []struct {
+		code, label string
+		ok          bool
+	}{
+		{"aut", "author", true},
+		{"ILL.", "illustrator", true},
+		{" trl ", "translator", true},
+		{"http://id.loc.gov/vocabulary/relators/edt", "editor", true},
+		{"https://id.loc.gov/vocabulary/relators/ctb", "contributor", true},
+		{"https://example.org/relators/aut", "", false},
+		{"xyz", "", false},
+	}
+	for _, tt := range tests {
+		if label, ok := Relator(tt.code); label != tt.label || ok != tt.ok {
+			t.Errorf("Relator(%q) = %q, %v, want %q, %v", tt.code, label, ok, tt.label, tt.ok)
+		}
+	}
+}
+
+// field returns the rows of a field occurrence with subfields given as
+// alternating codes and values.
+func field(tag string, ord int16, sfs ...string) []srs.Marc {
+	var rows []srs.Marc
+	for i := 0; i+1 < len(sfs); i += 2 {
+		rows = append(rows, srs.Marc{Field: tag, Ord: ord, Ind1: "1", Ind2: " ", SF: sfs[i], Content: sfs[i+1]})
+	}
+	return rows
+}
+
+func TestExtract(t *testing.T) {
+	var mrecs []srs.Marc
+	mrecs = append(mrecs, field("100", 1, "a", "Grahame, Kenneth,", "d", "1859-1932,", "e", "author.",
+		"4", "aut", "0", "http://id.loc.gov/authorities/names/n79032058")...)
+	mrecs = append(mrecs, field("245", 1, "a", "The wind in the willows")...)
+	mrecs = append(mrecs, field("700", 1, "a", "Shepard, Ernest H.", "q", "(Ernest Howard),",
+		"d", "(1879-1976)", "4", "https://id.loc.gov/vocabulary/relators/ill", "4", "xyz")...)
+	mrecs = append(mrecs, field("711", 1, "a", "Conference on Rivers", "n", "(2nd :", "c", "Oxford)",
+		"j", "Host,", "e", "Session.")...)
+	mrecs = append(mrecs, field("710", 1, "e", "publisher.")...)
+	want := []Contributor{
+		{Field: "100", Ord: 1, Type: Personal, Main: true, Name: "Grahame, Kenneth", Dates: "1859-1932",
+			Roles: []string{"author"}, RelatorCodes: []string{"aut"},
+			AuthorityURIs: []string{"http://id.loc.gov/authorities/names/n79032058"}},
+		{Field: "700", Ord: 1, Type: Personal, Name: "Shepard, Ernest H. (Ernest Howard)", Dates: "1879-1976",
+			Roles: []string{"illustrator"}, RelatorCodes: []string{"ill", "xyz"}},
+		{Field: "711", Ord: 1, Type: Meeting, Name: "Conference on Rivers (2nd : Oxford) Session",
+			Roles: []string{"Host"}},
+	}
+	if got := Extract(mrecs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,299 @@
+// Package derived writes tables that are derived from transformed SRS MARC
+// records, in addition to the main tabular output.
+package derived
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/uuid"
+)
+
+// TempSchema is the schema in which derived tables are created during a full
+// update, before they are moved to their final schema.
+const TempSchema = "marctab"
+
+// flushSize is the number of buffered rows for a table that causes the buffer
+// to be copied to the database.
+const flushSize = 10000
+
+// Record is a transformed SRS MARC record from which derived rows are
+// generated.  InstanceID is the instance, holdings, or authority identifier,
+// depending on the record type.  Anomalies lists any anomalies found while
+// transforming the record.
+type Record struct {
+	SRSID      string
+	InstanceID string
+	// Leader is the leader of the SRS record, which is not included in
+	// Marc if the record has no 001 field.
+	Leader    string
+	Marc      []srs.Marc
+	Anomalies []srs.Anomaly
+}
+
+// Column defines a column in a derived table.  The srs_id and identifier
+// columns are included in every derived table and are not defined as Column
+// values.
+type Column struct {
+	Name string
+	Type string
+}
+
+// Table defines a derived table.
+type Table struct {
+	// Schema and Name locate the final table.
+	Schema  string
+	Name    string
+	Comment string
+	Columns []Column
+	// IDColumn is the name of the identifier column, which defaults to
+	// instance_id.
+	IDColumn string
+	// Index lists columns to be indexed in addition to srs_id and the
+	// identifier column.
+	Index []string
+	// Rows returns the rows derived from a record, not including the srs_id
+	// and identifier values.
+	Rows func(r *Record) [][]any
+	// Report, if not nil, causes rows to be written to Report in CSV format
+	// instead of to the database.
+	Report *csv.Writer
+}
+
+// Final returns the schema-qualified name of the final table.
+func (t *Table) Final() string {
+	return t.Schema + "." + t.Name
+}
+
+// Temp returns the schema-qualified name of the table used during a full
+// update.
+func (t *Table) Temp() string {
+	return TempSchema + "._" + t.Name
+}
+
+// ColumnNames returns the names of all columns in the table, including srs_id
+// and the identifier column.
+func (t *Table) ColumnNames() []string {
+	names := []string{"srs_id", t.idColumn()}
+	for _, c := range t.Columns {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// DB is implemented by *pgx.Conn and pgx.Tx.
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// Writer buffers derived rows and copies them to the database.
+type Writer struct {
+	db     DB
+	tables []*Table
+	temp   bool
+	bufs   [][][]any
+}
+
+// NewWriter returns a Writer that writes rows derived from records to tables.
+// If temp is true, rows are written to the temporary tables used during a
+// full update; otherwise they are written to the final tables.
+func NewWriter(db DB, tables []*Table, temp bool) *Writer {
+	return &Writer{
+		db:     db,
+		tables: tables,
+		temp:   temp,
+		bufs:   make([][][]any, len(tables)),
+	}
+}
+
+// Write generates derived rows from a record and buffers them for writing.
+func (w *Writer) Write(ctx context.Context, r *Record) error {
+	srsID, err := uuid.EncodeUUID(r.SRSID)
+	if err != nil {
+		return fmt.Errorf("encoding srs_id: %v", err)
+	}
+	instanceID, err := uuid.EncodeUUID(r.InstanceID)
+	if err != nil {
+		instanceID = uuid.EncodeNilUUID()
+	}
+	for i, t := range w.tables {
+		for _, row := range t.Rows(r) {
+			if t.Report != nil {
+				rec := []string{r.SRSID, r.InstanceID}
+				for _, v := range row {
+					rec = append(rec, formatValue(v))
+				}
+				if err = t.Report.Write(rec); err != nil {
+					return fmt.Errorf("writing report: %s: %v", t.Name, err)
+				}
+				continue
+			}
+			w.bufs[i] = append(w.bufs[i], append([]any{srsID, instanceID}, row...))
+		}
+		if len(w.bufs[i]) >= flushSize {
+			if err = w.flush(ctx, i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Delete removes all derived rows for an SRS record.  Any rows buffered for
+// the record are not affected.
+func (w *Writer) Delete(ctx context.Context, srsID string) error {
+	for _, t := range w.tables {
+		if t.Report != nil {
+			continue
+		}
+		q := "DELETE FROM " + w.name(t) + " WHERE srs_id=$1"
+		if _, err := w.db.Exec(ctx, q, srsID); err != nil {
+			return fmt.Errorf("deleting from derived table: %s: %v", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// Flush writes all buffered rows.
+func (w *Writer) Flush(ctx context.Context) error {
+	for i, t := range w.tables {
+		if err := w.flush(ctx, i); err != nil {
+			return err
+		}
+		if t.Report != nil {
+			t.Report.Flush()
+			if err := t.Report.Error(); err != nil {
+				return fmt.Errorf("writing report: %s: %v", t.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Writer) flush(ctx context.Context, i int) error {
+	if len(w.bufs[i]) == 0 {
+		return nil
+	}
+	t := w.tables[i]
+	schema, name := t.Schema, t.Name
+	if w.temp {
+		schema, name = TempSchema, "_"+t.Name
+	}
+	_, err := w.db.CopyFrom(ctx, pgx.Identifier{schema, name}, t.ColumnNames(), pgx.CopyFromRows(w.bufs[i]))
+	if err != nil {
+		return fmt.Errorf("copying to derived table: %s: %v", t.Name, err)
+	}
+	w.bufs[i] = w.bufs[i][:0]
+	return nil
+}
+
+func (t *Table) idColumn() string {
+	if t.IDColumn == "" {
+		return "instance_id"
+	}
+	return t.IDColumn
+}
+
+func (w *Writer) name(t *Table) string {
+	if w.temp {
+		return t.Temp()
+	}
+	return t.Final()
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, "; ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Create creates the temporary table for t, replacing any existing table.
+func Create(ctx context.Context, db DB, t *Table) error {
+	_, _ = db.Exec(ctx, "DROP TABLE IF EXISTS "+t.Temp())
+	q := "CREATE TABLE " + t.Temp() + " (srs_id uuid NOT NULL, " + t.idColumn() + " uuid NOT NULL"
+	// Column names are quoted, because a column defined in a mapping
+	// configuration may have a name such as "order" that is a keyword.
+	for _, c := range t.Columns {
+		q += ", " + pgx.Identifier{c.Name}.Sanitize() + " " + c.Type
+	}
+	q += ")"
+	if _, err := db.Exec(ctx, q); err != nil {
+		return fmt.Errorf("creating derived table: %s: %v", t.Name, err)
+	}
+	if t.Comment != "" {
+		q = "COMMENT ON TABLE " + t.Temp() + " IS '" + strings.ReplaceAll(t.Comment, "'", "''") + "'"
+		if _, err := db.Exec(ctx, q); err != nil {
+			return fmt.Errorf("adding comment on derived table: %s: %v", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// Index creates indexes on the temporary table for t.
+func Index(ctx context.Context, db DB, t *Table) error {
+	cols := append([]string{"srs_id", t.idColumn()}, t.Index...)
+	for _, c := range cols {
+		q := "CREATE INDEX ON " + t.Temp() + " (" + pgx.Identifier{c}.Sanitize() + ")"
+		if _, err := db.Exec(ctx, q); err != nil {
+			return fmt.Errorf("creating index: %s (%s): %v", t.Name, c, err)
+		}
+	}
+	return nil
+}
+
+// Replace moves the temporary table for t to its final location, replacing
+// any existing table.
+func Replace(ctx context.Context, db DB, t *Table) error {
+	q := "DROP TABLE IF EXISTS " + t.Final()
+	if _, err := db.Exec(ctx, q); err != nil {
+		return fmt.Errorf("dropping table: %s", err)
+	}
+	if t.Schema == TempSchema {
+		q = "ALTER TABLE " + t.Temp() + " RENAME TO " + t.Name
+		if _, err := db.Exec(ctx, q); err != nil {
+			return fmt.Errorf("renaming table: %s", err)
+		}
+		return nil
+	}
+	q = "DROP TABLE IF EXISTS " + TempSchema + "." + t.Name
+	if _, err := db.Exec(ctx, q); err != nil {
+		return fmt.Errorf("dropping table: %s", err)
+	}
+	q = "ALTER TABLE " + t.Temp() + " RENAME TO " + t.Name
+	if _, err := db.Exec(ctx, q); err != nil {
+		return fmt.Errorf("renaming table: %s", err)
+	}
+	q = "ALTER TABLE " + TempSchema + "." + t.Name + " SET SCHEMA " + t.Schema
+	if _, err := db.Exec(ctx, q); err != nil {
+		return fmt.Errorf("moving table: %s", err)
+	}
+	return nil
+}
+
+// Exists reports whether the final table for t exists.
+func Exists(ctx context.Context, dc *pgx.Conn, t *Table) (bool, error) {
+	q := "SELECT 1 FROM information_schema.tables WHERE table_schema=$1 AND table_name=$2"
+	var i int64
+	err := dc.QueryRow(ctx, q, t.Schema, t.Name).Scan(&i)
+	switch {
+	case err == pgx.ErrNoRows:
+		return false, nil
+	case err != nil:
+		return false, err
+	default:
+		return true, nil
+	}
+}
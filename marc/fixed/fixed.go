@@ -0,0 +1,234 @@
+// Package fixed decodes the MARC 21 leader and fixed-length control fields
+// into named elements.
+package fixed
+
+import (
+	"strconv"
+	"time"
+)
+
+// Kind is the data type of a decoded element.
+type Kind int
+
+const (
+	// Text is a character string, which retains blanks and fill characters.
+	Text Kind = iota
+	// Int is a non-negative integer, or nil if not numeric.
+	Int
+	// Date is a date in the form yymmdd, or nil if not valid.
+	Date
+)
+
+// Position defines an element of a fixed-length field.
+type Position struct {
+	Name   string
+	Start  int
+	Length int
+	Kind   Kind
+}
+
+// Material configurations of 008/18-34, selected by leader/06-07.
+const (
+	Books         = "books"
+	ContinuingRes = "continuing_resources"
+	Maps          = "maps"
+	Music         = "music"
+	VisualMat     = "visual_materials"
+	ComputerFiles = "computer_files"
+	MixedMat      = "mixed_materials"
+)
+
+// Leader defines the elements of the leader.
+var Leader = []Position{
+	{Name: "record_length", Start: 0, Length: 5, Kind: Int},
+	{Name: "record_status", Start: 5, Length: 1},
+	{Name: "type_of_record", Start: 6, Length: 1},
+	{Name: "bibliographic_level", Start: 7, Length: 1},
+	{Name: "type_of_control", Start: 8, Length: 1},
+	{Name: "character_coding_scheme", Start: 9, Length: 1},
+	{Name: "base_address_of_data", Start: 12, Length: 5, Kind: Int},
+	{Name: "encoding_level", Start: 17, Length: 1},
+	{Name: "descriptive_cataloging_form", Start: 18, Length: 1},
+	{Name: "multipart_resource_record_level", Start: 19, Length: 1},
+}
+
+// Common008 defines the elements of 008 that are common to all materials.
+var Common008 = []Position{
+	{Name: "date_entered", Start: 0, Length: 6, Kind: Date},
+	{Name: "type_of_date", Start: 6, Length: 1},
+	{Name: "date1", Start: 7, Length: 4},
+	{Name: "date2", Start: 11, Length: 4},
+	{Name: "place_of_publication", Start: 15, Length: 3},
+	{Name: "language", Start: 35, Length: 3},
+	{Name: "modified_record", Start: 38, Length: 1},
+	{Name: "cataloging_source", Start: 39, Length: 1},
+}
+
+// Material008 defines the elements of 008/18-34 for each material
+// configuration.  The same elements are found in 006/01-17.
+var Material008 = map[string][]Position{
+	Books: {
+		{Name: "illustrations", Start: 18, Length: 4},
+		{Name: "target_audience", Start: 22, Length: 1},
+		{Name: "form_of_item", Start: 23, Length: 1},
+		{Name: "nature_of_contents", Start: 24, Length: 4},
+		{Name: "government_publication", Start: 28, Length: 1},
+		{Name: "conference_publication", Start: 29, Length: 1},
+		{Name: "festschrift", Start: 30, Length: 1},
+		{Name: "index", Start: 31, Length: 1},
+		{Name: "literary_form", Start: 33, Length: 1},
+		{Name: "biography", Start: 34, Length: 1},
+	},
+	ContinuingRes: {
+		{Name: "frequency", Start: 18, Length: 1},
+		{Name: "regularity", Start: 19, Length: 1},
+		{Name: "type_of_continuing_resource", Start: 21, Length: 1},
+		{Name: "form_of_original_item", Start: 22, Length: 1},
+		{Name: "form_of_item", Start: 23, Length: 1},
+		{Name: "nature_of_entire_work", Start: 24, Length: 1},
+		{Name: "nature_of_contents", Start: 25, Length: 3},
+		{Name: "government_publication", Start: 28, Length: 1},
+		{Name: "conference_publication", Start: 29, Length: 1},
+		{Name: "original_alphabet_or_script", Start: 33, Length: 1},
+		{Name: "entry_convention", Start: 34, Length: 1},
+	},
+	Maps: {
+		{Name: "relief", Start: 18, Length: 4},
+		{Name: "projection", Start: 22, Length: 2},
+		{Name: "type_of_cartographic_material", Start: 25, Length: 1},
+		{Name: "government_publication", Start: 28, Length: 1},
+		{Name: "form_of_item", Start: 29, Length: 1},
+		{Name: "index", Start: 31, Length: 1},
+		{Name: "special_format_characteristics", Start: 33, Length: 2},
+	},
+	Music: {
+		{Name: "form_of_composition", Start: 18, Length: 2},
+		{Name: "format_of_music", Start: 20, Length: 1},
+		{Name: "music_parts", Start: 21, Length: 1},
+		{Name: "target_audience", Start: 22, Length: 1},
+		{Name: "form_of_item", Start: 23, Length: 1},
+		{Name: "accompanying_matter", Start: 24, Length: 6},
+		{Name: "literary_text", Start: 30, Length: 2},
+		{Name: "transposition_and_arrangement", Start: 33, Length: 1},
+	},
+	VisualMat: {
+		{Name: "running_time", Start: 18, Length: 3, Kind: Int},
+		{Name: "target_audience", Start: 22, Length: 1},
+		{Name: "government_publication", Start: 28, Length: 1},
+		{Name: "form_of_item", Start: 29, Length: 1},
+		{Name: "type_of_visual_material", Start: 33, Length: 1},
+		{Name: "technique", Start: 34, Length: 1},
+	},
+	ComputerFiles: {
+		{Name: "target_audience", Start: 22, Length: 1},
+		{Name: "form_of_item", Start: 23, Length: 1},
+		{Name: "type_of_computer_file", Start: 26, Length: 1},
+		{Name: "government_publication", Start: 28, Length: 1},
+	},
+	MixedMat: {
+		{Name: "form_of_item", Start: 23, Length: 1},
+	},
+}
+
+// Materials lists the material configurations in a fixed order.
+var Materials = []string{Books, ContinuingRes, Maps, Music, VisualMat, ComputerFiles, MixedMat}
+
+// Material returns the 008 material configuration selected by leader/06-07,
+// or "" if the type of record is not defined.
+func Material(leader string) string {
+	v, ok := Slice(leader, 6, 8)
+	if !ok || len(v) != 2 {
+		return ""
+	}
+	return material(v[0], v[1])
+}
+
+func material(typeOfRecord, bibLevel byte) string {
+	switch typeOfRecord {
+	case 'a', 't':
+		switch bibLevel {
+		case 'b', 'i', 's':
+			return ContinuingRes
+		default:
+			return Books
+		}
+	case 'e', 'f':
+		return Maps
+	case 'c', 'd', 'i', 'j':
+		return Music
+	case 'g', 'k', 'o', 'r':
+		return VisualMat
+	case 'm':
+		return ComputerFiles
+	case 'p':
+		return MixedMat
+	default:
+		return ""
+	}
+}
+
+// Code extracts the element defined by p from s, shifted left by offset
+// characters, as it appears in s.  It returns false if s is too short to
+// contain the element.
+func Code(s string, p Position, offset int) (string, bool) {
+	start := p.Start - offset
+	return Slice(s, start, start+p.Length)
+}
+
+// Slice returns the characters of s from position start up to but not
+// including end, counting characters rather than bytes so that an element is
+// never cut within a multibyte character.  It returns false if s is too short
+// to contain the characters.
+func Slice(s string, start, end int) (string, bool) {
+	if start < 0 || end < start || end > len(s) {
+		return "", false
+	}
+	b, n := -1, 0
+	for i := range s {
+		if n == start {
+			b = i
+		}
+		if n == end {
+			return s[b:i], true
+		}
+		n++
+	}
+	if n == start {
+		b = len(s)
+	}
+	if n == end {
+		return s[b:], true
+	}
+	return "", false
+}
+
+// Value extracts the element defined by p from s, shifted left by offset
+// characters, and converts it according to p.Kind.  It returns nil if s is too
+// short to contain the element or the element cannot be converted.
+func Value(s string, p Position, offset int) any {
+	v, ok := Code(s, p, offset)
+	if !ok {
+		return nil
+	}
+	switch p.Kind {
+	case Int:
+		i, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || i < 0 {
+			return nil
+		}
+		return int32(i)
+	case Date:
+		// MARC records were first created in 1968.
+		century := "20"
+		if v >= "68" {
+			century = "19"
+		}
+		d, err := time.Parse("20060102", century+v)
+		if err != nil {
+			return nil
+		}
+		return d
+	default:
+		return v
+	}
+}
@@ -0,0 +1,85 @@
+package fixed
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSlice(t *testing.T) {
+	tests := []struct {
+		s          string
+		start, end int
+		want       string
+		ok         bool
+	}{
+		{"abcdef", 1, 3, "bc", true},
+		{"abcdef", 0, 6, "abcdef", true},
+		{"abcdef", 6, 6, "", true},
+		{"abcdef", 4, 7, "", false},
+		{"abcdef", -1, 2, "", false},
+		{"añcdef", 1, 3, "ñc", true},
+		{"ññññ", 2, 4, "ññ", true},
+		{"ñññ", 2, 4, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Slice(tt.s, tt.start, tt.end)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Slice(%q, %d, %d) = %q, %v, want %q, %v", tt.s, tt.start, tt.end, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	f008 := "980101s1998    nyu           000 0 eng d"
+	tests := []struct {
+		s    string
+		p    Position
+		want any
+	}{
+		{f008, Position{Start: 0, Length: 6, Kind: Date}, time.Date(1998, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{f008, Position{Start: 7, Length: 4, Kind: Int}, int32(1998)},
+		{f008, Position{Start: 35, Length: 3}, "eng"},
+		{f008, Position{Start: 38, Length: 3}, nil},
+		{"9801ñ1s1998    nyu           000 0 fré d", Position{Start: 35, Length: 3}, "fré"},
+		{"9801ñ1s1998    nyu           000 0 fré d", Position{Start: 0, Length: 6, Kind: Date}, nil},
+	}
+	for _, tt := range tests {
+		if got := Value(tt.s, tt.p, 0); got != tt.want {
+			t.Errorf("Value(%q, %+v) = %v, want %v", tt.s, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestDecode007(t *testing.T) {
+	tests := []struct {
+		s        string
+		category string
+		want     []Element
+	}{
+		{"ta", "text", []Element{
+			{Position: 0, Name: "category_of_material", Code: "t", Value: "text"},
+			{Position: 1, Name: "specific_material_designation", Code: "a", Value: "regular print"},
+		}},
+		{"aj ca", "map", []Element{
+			{Position: 0, Name: "category_of_material", Code: "a", Value: "map"},
+			{Position: 1, Name: "specific_material_designation", Code: "j", Value: "map"},
+			{Position: 3, Name: "color", Code: "c", Value: "multicolored"},
+			{Position: 4, Name: "physical_medium", Code: "a", Value: "paper"},
+		}},
+		{"aé ca", "map", []Element{
+			{Position: 0, Name: "category_of_material", Code: "a", Value: "map"},
+			{Position: 1, Name: "specific_material_designation", Code: "é"},
+			{Position: 3, Name: "color", Code: "c", Value: "multicolored"},
+			{Position: 4, Name: "physical_medium", Code: "a", Value: "paper"},
+		}},
+		{"ña", "", []Element{{Position: 0, Name: "category_of_material", Code: "ñ"}}},
+		{"", "", nil},
+	}
+	for _, tt := range tests {
+		category, got := Decode007(tt.s)
+		if category != tt.category || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Decode007(%q) = %q, %+v, want %q, %+v", tt.s, category, got, tt.category, tt.want)
+		}
+	}
+}
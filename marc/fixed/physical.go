@@ -0,0 +1,181 @@
+package fixed
+
+import (
+	"bufio"
+	_ "embed"
+	"strconv"
+	"strings"
+)
+
+// Element is a decoded element of a 006 or 007 field.
+type Element struct {
+	// Position is the starting character position within the field.
+	Position int
+	Name     string
+	Code     string
+	// Value is the meaning of Code, or "" if it is not defined.
+	Value string
+}
+
+type category struct {
+	name     string
+	elements []element
+}
+
+type element struct {
+	pos    Position
+	values map[string]string
+}
+
+//go:embed physical.txt
+var physicalText string
+
+var categories, elementValues = parsePhysical(physicalText)
+
+func parsePhysical(text string) (map[byte]*category, map[string]map[string]string) {
+	cats := make(map[byte]*category)
+	elems := make(map[string]map[string]string)
+	var cat *category
+	var values map[string]string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		switch {
+		case line[0] == ' ':
+			if values == nil || len(f) < 2 {
+				panic("invalid code value: " + line)
+			}
+			values[strings.ReplaceAll(f[0], "#", " ")] = strings.Join(f[1:], " ")
+		case f[0] == "category":
+			if len(f) < 3 || len(f[1]) != 1 {
+				panic("invalid category: " + line)
+			}
+			cat = &category{name: strings.Join(f[2:], " ")}
+			cats[f[1][0]] = cat
+			values = nil
+		case f[0] == "element":
+			if len(f) != 2 {
+				panic("invalid element: " + line)
+			}
+			cat = nil
+			values = make(map[string]string)
+			elems[f[1]] = values
+		default:
+			start, err1 := strconv.Atoi(f[0])
+			length, err2 := strconv.Atoi(f[1])
+			if cat == nil || len(f) != 3 || err1 != nil || err2 != nil {
+				panic("invalid element position: " + line)
+			}
+			values = make(map[string]string)
+			cat.elements = append(cat.elements, element{
+				pos:    Position{Name: f[2], Start: start, Length: length},
+				values: values,
+			})
+		}
+	}
+	return cats, elems
+}
+
+// Decode007 decodes a 007 field according to its category of material,
+// given by 007/00.  It returns the name of the category, or "" if the
+// category is not defined, and the decoded elements including the category
+// itself.
+func Decode007(s string) (string, []Element) {
+	if s == "" {
+		return "", nil
+	}
+	code, _ := Slice(s, 0, 1)
+	c, ok := categories[code[0]]
+	if !ok || len(code) != 1 {
+		return "", []Element{{Position: 0, Name: "category_of_material", Code: code}}
+	}
+	elements := []Element{{Position: 0, Name: "category_of_material", Code: code, Value: c.name}}
+	for _, e := range c.elements {
+		if v, ok := Value(s, e.pos, 0).(string); ok {
+			elements = append(elements, Element{
+				Position: e.pos.Start,
+				Name:     e.pos.Name,
+				Code:     v,
+				Value:    label(e.values, v),
+			})
+		}
+	}
+	return c.name, elements
+}
+
+// Decode006 decodes a 006 field according to its form of material, given by
+// 006/00.  It returns the material configuration, or "" if the form of
+// material is not defined, and the decoded elements including the form of
+// material itself.
+func Decode006(s string) (string, []Element) {
+	if s == "" {
+		return "", nil
+	}
+	code, _ := Slice(s, 0, 1)
+	elements := []Element{{
+		Position: 0,
+		Name:     "form_of_material",
+		Code:     code,
+		Value:    label(elementValues["form_of_material"], code),
+	}}
+	var material string
+	if len(code) == 1 {
+		material = Material006(code[0])
+	}
+	for _, p := range Material008[material] {
+		// Elements such as running time are written as codes, not
+		// converted, so that values such as "nnn" are kept.
+		if v, ok := Code(s, p, 17); ok {
+			elements = append(elements, Element{
+				Position: p.Start - 17,
+				Name:     p.Name,
+				Code:     v,
+				Value:    label(elementValues[p.Name], v),
+			})
+		}
+	}
+	return material, elements
+}
+
+// Material006 returns the material configuration selected by 006/00, or ""
+// if the form of material is not defined.
+func Material006(form byte) string {
+	if form == 's' {
+		return ContinuingRes
+	}
+	return material(form, ' ')
+}
+
+// label returns the meaning of code.  A code of more than one character that
+// is not defined as a whole is looked up by character, ignoring blanks.
+func label(values map[string]string, code string) string {
+	if code == "" {
+		return ""
+	}
+	if strings.Trim(code, "|") == "" {
+		return "no attempt to code"
+	}
+	if v, ok := values[code]; ok {
+		return v
+	}
+	if len(code) == 1 {
+		return ""
+	}
+	if strings.TrimSpace(code) == "" {
+		return values[" "]
+	}
+	var labels []string
+	for _, c := range code {
+		if c == ' ' || c == '|' {
+			continue
+		}
+		if v, ok := values[string(c)]; ok {
+			labels = append(labels, v)
+		}
+	}
+	return strings.Join(labels, "; ")
+}
@@ -0,0 +1,217 @@
+// Package format classifies transformed SRS MARC records by resource type,
+// e.g. book, ebook, serial, DVD, score, or map, according to a rule table.
+package format
+
+import (
+	"bufio"
+	"crypto/md5"
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/fixed"
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+// Unknown is the format of a record that matches no rule.
+const Unknown = "unknown"
+
+// Rules is an ordered list of classification rules.
+type Rules struct {
+	rules []rule
+	// text is the source of the rules.
+	text string
+}
+
+type rule struct {
+	format string
+	// line is the line number of the rule in the source.
+	line int
+	// conds lists the conditions grouped by tag, so that conditions on a
+	// repeatable field are tested against the same field.
+	conds [][]condition
+}
+
+type condition struct {
+	tag string
+	// sf is the subfield code, or "" for a position condition.
+	sf string
+	// start and end are character positions within the leader or a
+	// control field.
+	start int
+	end   int
+	// contains causes a case-insensitive substring match instead of an
+	// exact match.
+	contains bool
+	values   []string
+}
+
+//go:embed formats.txt
+var defaultText string
+
+// Default is the embedded rule table.
+var Default = mustParse(defaultText)
+
+func mustParse(text string) *Rules {
+	r, err := Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Load reads a rule table from a file.
+func Load(filename string) (*Rules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return r, nil
+}
+
+var condPattern = regexp.MustCompile(`^(leader|\d{3})(?:/(\d{2})(?:-(\d{2}))?|\$([0-9a-z]))(=|~)(.+)$`)
+
+// Parse parses a rule table in the format of the embedded rule table
+// formats.txt.
+func Parse(text string) (*Rules, error) {
+	rs := &Rules{text: text}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	var n int
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			return nil, fmt.Errorf("line %d: rule has no conditions", n)
+		}
+		r := rule{format: f[0], line: n}
+		groups := make(map[string]int)
+		for _, s := range f[1:] {
+			c, err := parseCondition(s)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			g, ok := groups[c.tag]
+			if !ok {
+				g = len(r.conds)
+				groups[c.tag] = g
+				r.conds = append(r.conds, nil)
+			}
+			r.conds[g] = append(r.conds[g], c)
+		}
+		rs.rules = append(rs.rules, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func parseCondition(s string) (condition, error) {
+	m := condPattern.FindStringSubmatch(s)
+	if m == nil {
+		return condition{}, fmt.Errorf("invalid condition %q", s)
+	}
+	c := condition{tag: m[1], sf: m[4], contains: m[5] == "~"}
+	if c.tag == "leader" {
+		c.tag = "000"
+	}
+	if c.sf == "" {
+		c.start, _ = strconv.Atoi(m[2])
+		c.end = c.start
+		if m[3] != "" {
+			c.end, _ = strconv.Atoi(m[3])
+		}
+		if c.end < c.start {
+			return condition{}, fmt.Errorf("invalid positions in condition %q", s)
+		}
+	}
+	for _, v := range strings.Split(m[6], ",") {
+		v = strings.ReplaceAll(v, "#", " ")
+		if c.contains {
+			v = strings.ToLower(v)
+		} else if c.sf == "" && len(v) != c.end-c.start+1 {
+			return condition{}, fmt.Errorf("value %q does not match positions in condition %q", v, s)
+		}
+		c.values = append(c.values, v)
+	}
+	return c, nil
+}
+
+// Signature returns a checksum of the rule table which can be compared with a
+// previous one.
+func (rs *Rules) Signature() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(rs.text)))
+}
+
+// Classify returns the format of a transformed record and the line number of
+// the rule that matched it, or Unknown and 0 if no rule matched.
+func (rs *Rules) Classify(mrecs []srs.Marc) (string, int) {
+	fields := srs.Fields(mrecs)
+	for _, r := range rs.rules {
+		if r.match(fields) {
+			return r.format, r.line
+		}
+	}
+	return Unknown, 0
+}
+
+func (r *rule) match(fields [][]srs.Marc) bool {
+	for _, group := range r.conds {
+		var ok bool
+		for _, f := range fields {
+			if f[0].Field == group[0].tag && matchAll(f, group) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAll(f []srs.Marc, conds []condition) bool {
+	for _, c := range conds {
+		if !match(f, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func match(f []srs.Marc, c condition) bool {
+	if c.sf == "" {
+		s, ok := fixed.Slice(f[0].Content, c.start, c.end+1)
+		return ok && c.matchValue(s)
+	}
+	for _, sf := range f {
+		if sf.SF == c.sf && c.matchValue(strings.TrimSpace(sf.Content)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *condition) matchValue(s string) bool {
+	if c.contains {
+		s = strings.ToLower(s)
+	}
+	for _, v := range c.values {
+		if c.contains && strings.Contains(s, v) || !c.contains && s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, text string
+		ok         bool
+	}{
+		{"valid", "# comment\n\nbook leader/06=a 008/23=#\nthesis leader/06=a 502$a~Thesis\n", true},
+		{"no conditions", "book\n", false},
+		{"invalid condition", "book leader/6=a\n", false},
+		{"value length", "book leader/06-07=a\n", false},
+		{"reversed positions", "book leader/07-06=am\n", false},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.text); (err == nil) != tt.ok {
+			t.Errorf("%s: Parse() error = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+// record returns the rows of a record having a leader with type of record
+// and bibliographic level typeLevel, an 008 with form of item form, and
+// other fields.
+func record(typeLevel string, form byte, fields ...srs.Marc) []srs.Marc {
+	f008 := []byte("980101s1998    nyu           000 0 eng d")
+	f008[23] = form
+	rows := []srs.Marc{
+		{Field: "000", Ord: 1, Content: "00714c" + typeLevel + " a2200205 a 4500"},
+		{Field: "008", Ord: 1, Content: string(f008)},
+	}
+	return append(rows, fields...)
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name  string
+		mrecs []srs.Marc
+		want  string
+	}{
+		{"book", record("am", ' '), "book"},
+		{"ebook by 008", record("am", 'o'), "ebook"},
+		{"ebook by 338", record("am", ' ', srs.Marc{Field: "338", Ord: 1, SF: "b", Content: "cr"}), "ebook"},
+		{"ebook by 245", record("am", ' ', srs.Marc{Field: "245", Ord: 1, SF: "h", Content: "[Electronic resource] /"}),
+			"ebook"},
+		{"serial", record("as", ' '), "serial"},
+		{"ejournal", record("as", 's'), "ejournal"},
+		{"microform", record("am", ' ', srs.Marc{Field: "007", Ord: 1, Content: "he bmb024baca"}), "microform"},
+		{"thesis", record("am", ' ', srs.Marc{Field: "502", Ord: 1, SF: "a", Content: "Thesis (Ph.D.)"}), "thesis"},
+		{"manuscript", record("tm", ' ', srs.Marc{Field: "502", Ord: 1, SF: "a", Content: "Thesis"}), "manuscript"},
+		{"music cd", record("jm", ' ', srs.Marc{Field: "007", Ord: 1, Content: "sd fsngnnmmned"}), "music_cd"},
+		// The conditions on 007 must be met by the same field.
+		{"music lp", record("jm", ' ', srs.Marc{Field: "007", Ord: 1, Content: "vd fvaizq"},
+			srs.Marc{Field: "007", Ord: 2, Content: "sd bsmennmplud"}), "music_lp"},
+		{"dvd", record("gm", ' ', srs.Marc{Field: "007", Ord: 1, Content: "vd cvaizq"}), "dvd"},
+		{"non-ASCII 008", []srs.Marc{
+			{Field: "000", Ord: 1, Content: "00714cam a2200205 a 4500"},
+			{Field: "008", Ord: 1, Content: "9801ñ1s1998    nyu     o     000 0 eng d"},
+		}, "ebook"},
+		{"unknown", record("zm", ' '), Unknown},
+		{"no leader", nil, Unknown},
+	}
+	for _, tt := range tests {
+		if got, _ := Default.Classify(tt.mrecs); got != tt.want {
+			t.Errorf("%s: Classify() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyOrder(t *testing.T) {
+	rs, err := Parse("# Rules are tried in order.\nfirst leader/06=a 008/23=o\nsecond leader/06=a\nthird leader/06=a\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		mrecs  []srs.Marc
+		format string
+		line   int
+	}{
+		{record("am", 'o'), "first", 2},
+		{record("am", ' '), "second", 3},
+		{record("gm", ' '), Unknown, 0},
+	}
+	for _, tt := range tests {
+		if format, line := rs.Classify(tt.mrecs); format != tt.format || line != tt.line {
+			t.Errorf("Classify(%q) = %q, %d, want %q, %d", tt.mrecs[0].Content, format, line, tt.format, tt.line)
+		}
+	}
+}
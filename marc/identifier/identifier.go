@@ -0,0 +1,204 @@
+// Package identifier extracts and normalizes standard identifiers (ISBN,
+// ISSN, LCCN, and OCLC number) from transformed SRS MARC records.
+package identifier
+
+import (
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+// Identifier types.
+const (
+	ISBN = "isbn"
+	ISSN = "issn"
+	LCCN = "lccn"
+	OCLC = "oclc"
+)
+
+// Identifier is a standard identifier found in a record.
+type Identifier struct {
+	Type string
+	// Raw is the content of the subfield.
+	Raw string
+	// Normalized is the normalized identifier, or "" if no identifier
+	// could be found in Raw.
+	Normalized string
+	// Valid reports whether the normalized identifier is well formed and,
+	// if it has a check digit, whether the check digit is correct.
+	Valid bool
+}
+
+// Extract returns the identifiers in 010$a (LCCN), 020$a (ISBN), 022$a
+// (ISSN), and 035$a (OCLC number) of a transformed record.
+func Extract(mrecs []srs.Marc) []Identifier {
+	var ids []Identifier
+	for _, m := range mrecs {
+		if m.SF != "a" || strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		var id Identifier
+		switch m.Field {
+		case "010":
+			id = NormalizeLCCN(m.Content)
+		case "020":
+			id = NormalizeISBN(m.Content)
+		case "022":
+			id = NormalizeISSN(m.Content)
+		case "035":
+			var ok bool
+			if id, ok = NormalizeOCLC(m.Content); !ok {
+				continue
+			}
+		default:
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// NormalizeISBN normalizes an ISBN, ignoring any qualifier such as
+// "(pbk.)" that follows it.  A valid ISBN-10 is converted to ISBN-13, so that
+// both forms of the same ISBN have the same normalized value.
+func NormalizeISBN(raw string) Identifier {
+	id := Identifier{Type: ISBN, Raw: raw}
+	s := strings.ToUpper(leadingToken(raw, "0123456789Xx-"))
+	s = strings.ReplaceAll(s, "-", "")
+	id.Normalized = s
+	switch len(s) {
+	case 10:
+		if isbn10Check(s[:9]) == s[9] {
+			id.Normalized = "978" + s[:9]
+			id.Normalized += string(isbn13Check(id.Normalized))
+			id.Valid = true
+		}
+	case 13:
+		id.Valid = isDigits(s) && (strings.HasPrefix(s, "978") || strings.HasPrefix(s, "979")) &&
+			isbn13Check(s[:12]) == s[12]
+	}
+	return id
+}
+
+func isbn10Check(s string) byte {
+	if !isDigits(s) {
+		return 0
+	}
+	var sum int
+	for i := 0; i < 9; i++ {
+		sum += int(s[i]-'0') * (10 - i)
+	}
+	c := (11 - sum%11) % 11
+	if c == 10 {
+		return 'X'
+	}
+	return byte('0' + c)
+}
+
+func isbn13Check(s string) byte {
+	if !isDigits(s) {
+		return 0
+	}
+	var sum int
+	for i := 0; i < 12; i++ {
+		d := int(s[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// NormalizeISSN normalizes an ISSN to the form "NNNN-NNNC".
+func NormalizeISSN(raw string) Identifier {
+	id := Identifier{Type: ISSN, Raw: raw}
+	s := strings.ToUpper(leadingToken(raw, "0123456789Xx- "))
+	s = strings.NewReplacer("-", "", " ", "").Replace(s)
+	if len(s) != 8 {
+		id.Normalized = s
+		return id
+	}
+	id.Normalized = s[:4] + "-" + s[4:]
+	if isDigits(s[:7]) {
+		var sum int
+		for i := 0; i < 7; i++ {
+			sum += int(s[i]-'0') * (8 - i)
+		}
+		c := byte('0' + (11-sum%11)%11)
+		if c == '0'+10 {
+			c = 'X'
+		}
+		id.Valid = s[7] == c
+	}
+	return id
+}
+
+// NormalizeLCCN normalizes an LCCN according to the rules of the LCCN
+// namespace:  blanks are removed, along with a forward slash and any
+// characters following it; and a hyphen is removed, with the characters
+// following it left-padded with zeros to a length of six.
+func NormalizeLCCN(raw string) Identifier {
+	id := Identifier{Type: LCCN, Raw: raw}
+	s := strings.Join(strings.Fields(raw), "")
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		s = s[:i]
+	}
+	if prefix, serial, ok := strings.Cut(s, "-"); ok {
+		if len(serial) < 6 {
+			serial = strings.Repeat("0", 6-len(serial)) + serial
+		}
+		s = prefix + serial
+	}
+	s = strings.ToLower(s)
+	id.Normalized = s
+	// A valid LCCN has an alphabetic prefix of up to three characters
+	// followed by eight digits, or up to two characters followed by ten
+	// digits.
+	n := len(s) - len(strings.TrimLeft(s, "abcdefghijklmnopqrstuvwxyz"))
+	digits := s[n:]
+	id.Valid = isDigits(digits) && ((len(digits) == 8 && n <= 3) || (len(digits) == 10 && n <= 2))
+	return id
+}
+
+// NormalizeOCLC normalizes an OCLC number having the prefix "(OCoLC)",
+// removing the prefix, any "ocm", "ocn", or "on" prefix, and leading zeros.
+// It reports false if raw is not an OCLC number.
+func NormalizeOCLC(raw string) (Identifier, bool) {
+	id := Identifier{Type: OCLC, Raw: raw}
+	s := strings.TrimSpace(raw)
+	if !strings.HasPrefix(strings.ToLower(s), "(ocolc)") {
+		return id, false
+	}
+	s = strings.TrimSpace(s[len("(ocolc)"):])
+	for _, p := range []string{"ocm", "ocn", "on"} {
+		if strings.HasPrefix(s, p) {
+			s = s[len(p):]
+			break
+		}
+	}
+	s = strings.TrimLeft(strings.TrimSpace(s), "0")
+	id.Normalized = s
+	id.Valid = isDigits(s)
+	return id, true
+}
+
+// leadingToken returns the first run of characters in s that are contained in
+// chars, after any leading blanks.
+func leadingToken(s, chars string) string {
+	s = strings.TrimLeft(s, " ")
+	i := 0
+	for i < len(s) && strings.IndexByte(chars, s[i]) >= 0 {
+		i++
+	}
+	return strings.TrimSpace(s[:i])
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return len(s) != 0
+}
@@ -0,0 +1,103 @@
+package identifier
+
+import "testing"
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		raw, normalized string
+		valid           bool
+	}{
+		{"0306406152", "9780306406157", true},
+		{"0-306-40615-2 (pbk.)", "9780306406157", true},
+		{"080442957X", "9780804429573", true},
+		{"080442957x", "9780804429573", true},
+		{"978-0-306-40615-7", "9780306406157", true},
+		{"9790306406156 (score)", "9790306406156", true},
+		{"0306406153", "0306406153", false},
+		{"9780306406158", "9780306406158", false},
+		{"9770306406159", "9770306406159", false},
+		{"03064061", "03064061", false},
+		{"(pbk.)", "", false},
+	}
+	for _, tt := range tests {
+		id := NormalizeISBN(tt.raw)
+		if id.Normalized != tt.normalized || id.Valid != tt.valid {
+			t.Errorf("NormalizeISBN(%q) = %q, %v, want %q, %v", tt.raw, id.Normalized, id.Valid,
+				tt.normalized, tt.valid)
+		}
+	}
+}
+
+func TestNormalizeISSN(t *testing.T) {
+	tests := []struct {
+		raw, normalized string
+		valid           bool
+	}{
+		{"0378-5955", "0378-5955", true},
+		{"00280836", "0028-0836", true},
+		{"2434-561x", "2434-561X", true},
+		{"0378 5955 (Print)", "0378-5955", true},
+		{"0378-5956", "0378-5956", false},
+		{"0378-595", "0378595", false},
+	}
+	for _, tt := range tests {
+		id := NormalizeISSN(tt.raw)
+		if id.Normalized != tt.normalized || id.Valid != tt.valid {
+			t.Errorf("NormalizeISSN(%q) = %q, %v, want %q, %v", tt.raw, id.Normalized, id.Valid,
+				tt.normalized, tt.valid)
+		}
+	}
+}
+
+func TestNormalizeLCCN(t *testing.T) {
+	// Examples from the LCCN namespace normalization rules.
+	tests := []struct {
+		raw, normalized string
+		valid           bool
+	}{
+		{"n78-890351", "n78890351", true},
+		{"n78-89035", "n78089035", true},
+		{"n 78890351 ", "n78890351", true},
+		{" 85000002 ", "85000002", true},
+		{"85-2 ", "85000002", true},
+		{"2001-000002", "2001000002", true},
+		{"75-425165//r75", "75425165", true},
+		{" 79139101 /AC/r932", "79139101", true},
+		{"abcd12345678", "abcd12345678", false},
+		{"n7889035", "n7889035", false},
+	}
+	for _, tt := range tests {
+		id := NormalizeLCCN(tt.raw)
+		if id.Normalized != tt.normalized || id.Valid != tt.valid {
+			t.Errorf("NormalizeLCCN(%q) = %q, %v, want %q, %v", tt.raw, id.Normalized, id.Valid,
+				tt.normalized, tt.valid)
+		}
+	}
+}
+
+func TestNormalizeOCLC(t *testing.T) {
+	tests := []struct {
+		raw, normalized string
+		ok, valid       bool
+	}{
+		{"(OCoLC)ocm00012345", "12345", true, true},
+		{"(OCoLC)ocn123456789", "123456789", true, true},
+		{"(OCoLC)on1234567890", "1234567890", true, true},
+		{"(OCoLC) 0012345", "12345", true, true},
+		{"(ocolc)12345", "12345", true, true},
+		{"(OCoLC)ocm12x45", "12x45", true, false},
+		{"(DLC)   85000002", "", false, false},
+		{"ocm00012345", "", false, false},
+	}
+	for _, tt := range tests {
+		id, ok := NormalizeOCLC(tt.raw)
+		if ok != tt.ok {
+			t.Errorf("NormalizeOCLC(%q) reported %v, want %v", tt.raw, ok, tt.ok)
+			continue
+		}
+		if ok && (id.Normalized != tt.normalized || id.Valid != tt.valid) {
+			t.Errorf("NormalizeOCLC(%q) = %q, %v, want %q, %v", tt.raw, id.Normalized, id.Valid,
+				tt.normalized, tt.valid)
+		}
+	}
+}
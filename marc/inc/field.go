@@ -0,0 +1,282 @@
+package inc
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/util"
+)
+
+// fieldKey identifies a field of a record in the output table.
+type fieldKey struct {
+	field string
+	ord   int16
+}
+
+// fieldRowSQL is an expression that encodes the content of a row of the
+// output table for a field checksum.  It must match writeFieldRow.
+const fieldRowSQL = "ind1 || chr(31) || ind2 || chr(31) || sf || chr(31) || content"
+
+// rowSQL is an expression that encodes a row of the output table, including
+// its line number, for a record checksum.  It must match writeRow.
+const rowSQL = "line::text || chr(31) || " + fieldRowSQL
+
+// fieldCksumSQL is an aggregate expression that computes the checksum of a
+// field from its rows in the output table.  It must match fieldCksums.
+const fieldCksumSQL = "md5(string_agg(" + fieldRowSQL + ", chr(30) ORDER BY line))"
+
+// writeRow encodes a row for a checksum in the same way as rowSQL.
+func writeRow(b *strings.Builder, m *srs.Marc) {
+	b.WriteString(strconv.Itoa(int(m.Line)))
+	b.WriteByte(31)
+	writeFieldRow(b, m)
+}
+
+// writeFieldRow encodes the content of a row for a field checksum in the same
+// way as fieldRowSQL.
+func writeFieldRow(b *strings.Builder, m *srs.Marc) {
+	b.WriteString(m.Ind1)
+	b.WriteByte(31)
+	b.WriteString(m.Ind2)
+	b.WriteByte(31)
+	b.WriteString(m.SF)
+	b.WriteByte(31)
+	b.WriteString(m.Content)
+}
+
+// fieldCksums returns the checksum of each field in a transformed record.
+// The line numbers are not included, so that a field is not considered to
+// have changed if only its position in the record has changed, e.g. because
+// another field has been inserted before it.
+func fieldCksums(mrecs []srs.Marc) map[fieldKey]string {
+	bufs := make(map[fieldKey]*strings.Builder)
+	for i := range mrecs {
+		m := &mrecs[i]
+		k := fieldKey{field: m.Field, ord: m.Ord}
+		b, ok := bufs[k]
+		if ok {
+			b.WriteByte(30)
+		} else {
+			b = new(strings.Builder)
+			bufs[k] = b
+		}
+		writeFieldRow(b, m)
+	}
+	sums := make(map[fieldKey]string, len(bufs))
+	for k, b := range bufs {
+		sums[k] = fmt.Sprintf("%x", md5.Sum([]byte(b.String())))
+	}
+	return sums
+}
+
+// createFieldCksum creates the field checksum table from the output table,
+// for records that have a record checksum.
+func createFieldCksum(ctx context.Context, tx pgx.Tx, srsMarctab string, mode *util.Mode) error {
+	fieldCksumTable := mode.FieldCksumTable()
+	q := "CREATE TABLE " + fieldCksumTable + " (id uuid NOT NULL,field varchar(3) NOT NULL,ord smallint NOT NULL,cksum text NOT NULL)"
+	if _, err := tx.Exec(ctx, q); err != nil {
+		return fmt.Errorf("creating field checksum table: %s", err)
+	}
+	q = "INSERT INTO " + fieldCksumTable + " (id,field,ord,cksum)" +
+		" SELECT mt.srs_id, mt.field, mt.ord, " + fieldCksumSQL + " FROM " + srsMarctab + " mt" +
+		" WHERE EXISTS (SELECT 1 FROM " + mode.CksumTable() + " c WHERE c.id = mt.srs_id)" +
+		" GROUP BY mt.srs_id, mt.field, mt.ord"
+	if _, err := tx.Exec(ctx, q); err != nil {
+		return fmt.Errorf("writing data to field checksum table: %s", err)
+	}
+	q = "ALTER TABLE " + fieldCksumTable + " ADD CONSTRAINT field_cksum" + mode.Suffix + "_pkey PRIMARY KEY (id,field,ord)"
+	if _, err := tx.Exec(ctx, q); err != nil {
+		return fmt.Errorf("indexing field checksum table: %s", err)
+	}
+	return nil
+}
+
+// writeFieldCksums writes the checksums of fields in a record, replacing any
+// existing ones.
+func writeFieldCksums(ctx context.Context, tx pgx.Tx, mode *util.Mode, id string, sums map[fieldKey]string) error {
+	q := "INSERT INTO " + mode.FieldCksumTable() + " VALUES($1,$2,$3,$4)" +
+		" ON CONFLICT (id,field,ord) DO UPDATE SET cksum = EXCLUDED.cksum"
+	for k, sum := range sums {
+		if _, err := tx.Exec(ctx, q, id, k.field, k.ord, sum); err != nil {
+			return fmt.Errorf("writing field checksum: %s", err)
+		}
+	}
+	return nil
+}
+
+// updateFields rewrites only the fields of a changed record whose checksums
+// differ from the stored ones.  If the columns that are common to all rows of
+// the record have changed, or if it has no rows in tablefinal, the whole
+// record is rewritten.  The line numbers of fields that have not changed but
+// have moved within the record are updated.  updateFields reports whether any
+// fields were rewritten.
+func updateFields(ctx context.Context, connR *pgx.Conn, tx pgx.Tx, tablefinal string, mode *util.Mode,
+	filter *srs.Filter, content *util.Content, id, matchedID, instanceHRID *string, instanceID string,
+	state *string, mrecs []srs.Marc) (bool, error) {
+	whole, err := recordChanged(ctx, connR, tablefinal, mode, filter, matchedID, instanceHRID, instanceID, state, *id)
+	if err != nil {
+		return false, err
+	}
+	sums := fieldCksums(mrecs)
+	if whole {
+		q := "DELETE FROM " + tablefinal + " WHERE srs_id=$1"
+		if _, err = tx.Exec(ctx, q, *id); err != nil {
+			return false, fmt.Errorf("deleting record: %s", err)
+		}
+		q = "DELETE FROM " + mode.FieldCksumTable() + " WHERE id=$1"
+		if _, err = tx.Exec(ctx, q, *id); err != nil {
+			return false, fmt.Errorf("deleting field checksums: %s", err)
+		}
+		if err = insertRows(ctx, tx, tablefinal, filter, content, id, matchedID, instanceHRID, instanceID, state, mrecs); err != nil {
+			return false, fmt.Errorf("rewriting record: %s", err)
+		}
+		return true, writeFieldCksums(ctx, tx, mode, *id, sums)
+	}
+	old := make(map[fieldKey]string)
+	q := "SELECT field, ord, cksum FROM " + mode.FieldCksumTable() + " WHERE id=$1"
+	rows, err := connR.Query(ctx, q, *id)
+	if err != nil {
+		return false, fmt.Errorf("reading field checksums: %s", err)
+	}
+	for rows.Next() {
+		var k fieldKey
+		var sum string
+		if err = rows.Scan(&k.field, &k.ord, &sum); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("reading field checksum: %s", err)
+		}
+		old[k] = sum
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return false, fmt.Errorf("reading field checksums: %s", err)
+	}
+	changed := make(map[fieldKey]string)
+	for k, sum := range sums {
+		if old[k] != sum {
+			changed[k] = sum
+		}
+	}
+	var deleted []fieldKey
+	for k := range old {
+		if _, ok := sums[k]; !ok {
+			deleted = append(deleted, k)
+		}
+	}
+	for _, k := range deleted {
+		if err = deleteField(ctx, tx, tablefinal, *id, k); err != nil {
+			return false, err
+		}
+		q = "DELETE FROM " + mode.FieldCksumTable() + " WHERE id=$1 AND field=$2 AND ord=$3"
+		if _, err = tx.Exec(ctx, q, *id, k.field, k.ord); err != nil {
+			return false, fmt.Errorf("deleting field checksum: %s", err)
+		}
+	}
+	for k := range changed {
+		if _, ok := old[k]; ok {
+			if err = deleteField(ctx, tx, tablefinal, *id, k); err != nil {
+				return false, err
+			}
+		}
+		var frecs []srs.Marc
+		for _, m := range mrecs {
+			if m.Field == k.field && m.Ord == k.ord {
+				frecs = append(frecs, m)
+			}
+		}
+		if err = insertRows(ctx, tx, tablefinal, filter, content, id, matchedID, instanceHRID, instanceID, state, frecs); err != nil {
+			return false, fmt.Errorf("rewriting field: %s", err)
+		}
+	}
+	if err = writeFieldCksums(ctx, tx, mode, *id, changed); err != nil {
+		return false, err
+	}
+	if err = moveFields(ctx, connR, tx, tablefinal, *id, changed, mrecs); err != nil {
+		return false, err
+	}
+	return len(deleted) != 0 || len(changed) != 0, nil
+}
+
+// moveFields updates the line numbers of the fields of a record in tablefinal
+// that have not changed, if they differ from those in the transformed record.
+func moveFields(ctx context.Context, connR *pgx.Conn, tx pgx.Tx, tablefinal string, id string,
+	changed map[fieldKey]string, mrecs []srs.Marc) error {
+	lines := make(map[fieldKey]int16)
+	for _, m := range mrecs {
+		k := fieldKey{field: m.Field, ord: m.Ord}
+		if _, ok := lines[k]; !ok {
+			lines[k] = m.Line
+		}
+	}
+	q := "SELECT field, ord, min(line) FROM " + tablefinal + " WHERE srs_id=$1 GROUP BY field, ord"
+	rows, err := connR.Query(ctx, q, id)
+	if err != nil {
+		return fmt.Errorf("reading line numbers: %s", err)
+	}
+	moved := make(map[fieldKey]int16)
+	for rows.Next() {
+		var k fieldKey
+		var line int16
+		if err = rows.Scan(&k.field, &k.ord, &line); err != nil {
+			rows.Close()
+			return fmt.Errorf("reading line number: %s", err)
+		}
+		if _, ok := changed[k]; ok {
+			continue
+		}
+		if l, ok := lines[k]; ok && l != line {
+			moved[k] = l - line
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("reading line numbers: %s", err)
+	}
+	q = "UPDATE " + tablefinal + " SET line = line + $4 WHERE srs_id=$1 AND field=$2 AND ord=$3"
+	for k, d := range moved {
+		if _, err = tx.Exec(ctx, q, id, k.field, k.ord, d); err != nil {
+			return fmt.Errorf("updating line numbers: %s", err)
+		}
+	}
+	return nil
+}
+
+// recordChanged reports whether the columns that are common to all rows of a
+// record in tablefinal differ from the transformed record, or the record has
+// no rows.
+func recordChanged(ctx context.Context, connR *pgx.Conn, tablefinal string, mode *util.Mode, filter *srs.Filter,
+	matchedID, instanceHRID *string, instanceID string, state *string, id string) (bool, error) {
+	q := "SELECT matched_id::text, " + mode.HRIDColumn + ", " + mode.IDColumn + "::text"
+	if filter.NonActual() {
+		q += ", state"
+	} else {
+		q += ", ''"
+	}
+	q += " FROM " + tablefinal + " WHERE srs_id=$1 LIMIT 1"
+	var m, h, i, s string
+	err := connR.QueryRow(ctx, q, id).Scan(&m, &h, &i, &s)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return true, nil
+	case err != nil:
+		return false, fmt.Errorf("checking for existing rows: %s", err)
+	}
+	if filter.NonActual() && s != *state {
+		return true, nil
+	}
+	return m != *matchedID || h != *instanceHRID || !strings.EqualFold(i, instanceID), nil
+}
+
+func deleteField(ctx context.Context, tx pgx.Tx, tablefinal string, id string, k fieldKey) error {
+	q := "DELETE FROM " + tablefinal + " WHERE srs_id=$1 AND field=$2 AND ord=$3"
+	if _, err := tx.Exec(ctx, q, id, k.field, k.ord); err != nil {
+		return fmt.Errorf("deleting field: %s", err)
+	}
+	return nil
+}
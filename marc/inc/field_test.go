@@ -0,0 +1,38 @@
+package inc
+
+import (
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+func TestFieldCksums(t *testing.T) {
+	before := []srs.Marc{
+		{Line: 1, Field: "001", Ord: 1, Content: "in00000001"},
+		{Line: 2, Field: "245", Ord: 1, Ind1: "1", Ind2: "0", SF: "a", Content: "Title"},
+		{Line: 3, Field: "650", Ord: 1, Ind1: " ", Ind2: "0", SF: "a", Content: "Libraries"},
+	}
+	// A 500 field is inserted, so that 650 moves to a later line, and
+	// the content of 245 is changed.
+	after := []srs.Marc{
+		{Line: 1, Field: "001", Ord: 1, Content: "in00000001"},
+		{Line: 2, Field: "245", Ord: 1, Ind1: "1", Ind2: "0", SF: "a", Content: "New title"},
+		{Line: 3, Field: "500", Ord: 1, Ind1: " ", Ind2: " ", SF: "a", Content: "Note"},
+		{Line: 4, Field: "650", Ord: 1, Ind1: " ", Ind2: "0", SF: "a", Content: "Libraries"},
+	}
+	old, sums := fieldCksums(before), fieldCksums(after)
+	tests := []struct {
+		k       fieldKey
+		changed bool
+	}{
+		{fieldKey{"001", 1}, false},
+		{fieldKey{"245", 1}, true},
+		{fieldKey{"500", 1}, true},
+		{fieldKey{"650", 1}, false},
+	}
+	for _, tt := range tests {
+		if changed := old[tt.k] != sums[tt.k]; changed != tt.changed {
+			t.Errorf("field %s/%d: changed = %v, want %v", tt.k.field, tt.k.ord, changed, tt.changed)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package inc
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/util"
+)
+
+// CksumIgnore is a set of tags that are excluded from change detection.  When
+// it is used, a record whose raw checksum has changed is rewritten only if
+// its transformed content, not including the ignored fields, has also
+// changed.
+type CksumIgnore struct {
+	patterns []string
+	tags     map[string]bool
+}
+
+// ParseCksumIgnore returns the set of tags matching a list of tag patterns,
+// in which "X" matches any digit, e.g. "9XX".  It returns nil if the list is
+// empty.
+func ParseCksumIgnore(patterns []string) (*CksumIgnore, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	c := &CksumIgnore{tags: make(map[string]bool)}
+	for _, p := range patterns {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if len(p) != 3 || strings.Trim(p, "0123456789X") != "" {
+			return nil, fmt.Errorf("invalid tag pattern: %s", p)
+		}
+		c.patterns = append(c.patterns, p)
+	}
+	for _, tag := range util.GetAllFieldNames() {
+		for _, p := range c.patterns {
+			if matchTag(p, tag) {
+				c.tags[tag] = true
+				break
+			}
+		}
+	}
+	return c, nil
+}
+
+func matchTag(pattern, tag string) bool {
+	for i := 0; i < len(tag); i++ {
+		if pattern[i] != tag[i] && pattern[i] != 'X' {
+			return false
+		}
+	}
+	return true
+}
+
+// Match reports whether tag matches any of the tag patterns.
+func (c *CksumIgnore) Match(tag string) bool {
+	return c.tags[tag]
+}
+
+// String returns the list of tag patterns.
+func (c *CksumIgnore) String() string {
+	return strings.Join(c.patterns, ",")
+}
+
+// cksumSQL returns an aggregate expression that computes the checksum of a
+// record from its rows in the output table.  It must match cksum.
+func (c *CksumIgnore) cksumSQL(mode *util.Mode, filter *srs.Filter) string {
+	state := "''"
+	if filter.NonActual() {
+		state = "min(state)"
+	}
+	tags := make([]string, 0, len(c.tags))
+	for _, tag := range util.GetAllFieldNames() {
+		if c.tags[tag] {
+			tags = append(tags, "'"+tag+"'")
+		}
+	}
+	return "md5(min(matched_id::text) || chr(31) || min(" + mode.HRIDColumn + ") || chr(31) || min(" + mode.IDColumn + "::text)" +
+		" || chr(31) || " + state + " || chr(30) || coalesce(string_agg(" + rowSQL + ", chr(30) ORDER BY line)" +
+		" FILTER (WHERE field NOT IN (" + strings.Join(tags, ",") + ")), ''))"
+}
+
+// cksum returns the checksum of a transformed record, not including the
+// ignored fields or fields that are not written to the output table.  The
+// state is included only if filter may select records that are not current.
+func (c *CksumIgnore) cksum(filter *srs.Filter, matchedID, instanceHRID, instanceID, state string,
+	mrecs []srs.Marc) string {
+	if !filter.NonActual() {
+		state = ""
+	}
+	var b strings.Builder
+	b.WriteString(matchedID)
+	b.WriteByte(31)
+	b.WriteString(instanceHRID)
+	b.WriteByte(31)
+	b.WriteString(strings.ToLower(instanceID))
+	b.WriteByte(31)
+	b.WriteString(state)
+	b.WriteByte(30)
+	first := true
+	for i := range mrecs {
+		m := &mrecs[i]
+		if c.tags[m.Field] || !isTag(m.Field) {
+			continue
+		}
+		if !first {
+			b.WriteByte(30)
+		}
+		first = false
+		writeRow(&b, m)
+	}
+	return fmt.Sprintf("%x", md5.Sum([]byte(b.String())))
+}
+
+// isTag reports whether a field is written to the output table.
+func isTag(field string) bool {
+	return len(field) == 3 && strings.Trim(field, "0123456789") == ""
+}
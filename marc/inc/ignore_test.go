@@ -0,0 +1,148 @@
+package inc
+
+import (
+	"crypto/md5"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/util"
+)
+
+// splitConcat splits an SQL expression at the top-level concatenation
+// operators.
+func splitConcat(expr string) []string {
+	var terms []string
+	var depth, start int
+	var quoted bool
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(expr[i:], " || "):
+			terms = append(terms, expr[start:i])
+			start = i + 4
+			i += 3
+		}
+	}
+	return append(terms, expr[start:])
+}
+
+// row is a row of the output table, with its columns as they are converted
+// to text by PostgreSQL.
+type row map[string]string
+
+// evalTerm evaluates a term of a concatenation in the checksum expressions
+// for a row, or for the rows of a record given by rows.
+func evalTerm(t *testing.T, term string, r row, rows []row) string {
+	aggPattern := regexp.MustCompile(`^coalesce\(string_agg\((.*), chr\(30\) ORDER BY line\)` +
+		` FILTER \(WHERE field NOT IN \((.*)\)\), ''\)$`)
+	switch {
+	case term == "chr(30)":
+		return "\x1e"
+	case term == "chr(31)":
+		return "\x1f"
+	case term == "''":
+		return ""
+	case strings.HasPrefix(term, "min(") && strings.HasSuffix(term, ")"):
+		return evalTerm(t, strings.TrimSuffix(strings.TrimPrefix(term, "min("), ")"), rows[0], nil)
+	case aggPattern.MatchString(term):
+		m := aggPattern.FindStringSubmatch(term)
+		sorted := append([]row(nil), rows...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			li, _ := strconv.Atoi(sorted[i]["line"])
+			lj, _ := strconv.Atoi(sorted[j]["line"])
+			return li < lj
+		})
+		var values []string
+		for _, rr := range sorted {
+			if !strings.Contains(m[2], "'"+rr["field"]+"'") {
+				values = append(values, evalConcat(t, m[1], rr, nil))
+			}
+		}
+		return strings.Join(values, "\x1e")
+	default:
+		v, ok := r[strings.TrimSuffix(term, "::text")]
+		if !ok {
+			t.Fatalf("unknown term in checksum expression: %s", term)
+		}
+		return v
+	}
+}
+
+func evalConcat(t *testing.T, expr string, r row, rows []row) string {
+	var b strings.Builder
+	for _, term := range splitConcat(expr) {
+		b.WriteString(evalTerm(t, term, r, rows))
+	}
+	return b.String()
+}
+
+// evalCksumSQL evaluates the aggregate expression returned by cksumSQL over
+// the rows of a record in the output table.
+func evalCksumSQL(t *testing.T, expr string, rows []row) string {
+	if !strings.HasPrefix(expr, "md5(") || !strings.HasSuffix(expr, ")") {
+		t.Fatalf("unexpected checksum expression: %s", expr)
+	}
+	s := evalConcat(t, strings.TrimSuffix(strings.TrimPrefix(expr, "md5("), ")"), nil, rows)
+	return fmt.Sprintf("%x", md5.Sum([]byte(s)))
+}
+
+func TestCksumMatchesSQL(t *testing.T) {
+	ignore, err := ParseCksumIgnore([]string{"005", "9xx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const matchedID = "6a4d2d2e-8f64-4b5c-9d47-2a3b4c5d6e7f"
+	const hrid = "in00000001"
+	// The instance ID is given in upper case, as it may appear in 999 $i,
+	// but the uuid column is converted to text in lower case.
+	const instanceID = "0E1D2C3B-4A59-4867-8756-A4B3C2D1E0F9"
+	mrecs := []srs.Marc{
+		{Line: 1, Field: "000", Ord: 1, Content: "00714cam a2200205 a 4500"},
+		{Line: 2, Field: "001", Ord: 1, Content: hrid},
+		{Line: 3, Field: "005", Ord: 1, Content: "20260101120000.0"},
+		{Line: 4, Field: "245", Ord: 1, Ind1: "1", Ind2: "0", SF: "a", Content: "Title :"},
+		{Line: 5, Field: "245", Ord: 1, Ind1: "1", Ind2: "0", SF: "b", Content: "subtitle"},
+		{Line: 6, Field: "650", Ord: 1, Ind1: " ", Ind2: "0", SF: "a", Content: "Libraries"},
+		{Line: 7, Field: "999", Ord: 1, Ind1: "f", Ind2: "f", SF: "i", Content: instanceID},
+	}
+	for _, state := range []string{"ACTUAL", "OLD"} {
+		filter := srs.NewFilter(srs.Bib)
+		filter.States = []string{"ACTUAL", "OLD"}
+		if state == "ACTUAL" {
+			filter.States = []string{"ACTUAL"}
+		}
+		// The rows are given to the SQL expression in reverse order, so
+		// that ORDER BY line is needed to match.
+		var rows []row
+		for i := len(mrecs) - 1; i >= 0; i-- {
+			m := mrecs[i]
+			rows = append(rows, row{
+				"line":                  strconv.Itoa(int(m.Line)),
+				"matched_id":            matchedID,
+				util.BibMode.HRIDColumn: hrid,
+				util.BibMode.IDColumn:   strings.ToLower(instanceID),
+				"state":                 state,
+				"field":                 m.Field,
+				"ind1":                  m.Ind1,
+				"ind2":                  m.Ind2,
+				"sf":                    m.SF,
+				"content":               m.Content,
+			})
+		}
+		want := evalCksumSQL(t, ignore.cksumSQL(util.BibMode, filter), rows)
+		if got := ignore.cksum(filter, matchedID, hrid, instanceID, state, mrecs); got != want {
+			t.Errorf("state %s: cksum() = %s, cksumSQL evaluates to %s", state, got, want)
+		}
+	}
+}
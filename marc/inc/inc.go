@@ -8,21 +8,25 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/library-data-platform/ldpmarc/marc/derived"
 	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/textnorm"
 	"github.com/library-data-platform/ldpmarc/marc/util"
 	"github.com/library-data-platform/ldpmarc/marc/uuid"
 )
 
-const schemaVersion int64 = 14
-const cksumTable = "marctab.cksum"
+const schemaVersion int64 = 16
 const metadataTableS = "marctab"
 const metadataTableT = "metadata"
-const metadataTable = metadataTableS + "." + metadataTableT
 
-func IncUpdateAvail(dc *pgx.Conn) (bool, error) {
+// IncUpdateAvail reports whether incremental update is available for a record
+// type.  The config string describes the record selection and output options
+// and must match the one used by the previous full update.
+func IncUpdateAvail(dc *pgx.Conn, mode *util.Mode, config string) (bool, error) {
 	var err error
+	metadataTable := mode.MetadataTable()
 	// check if metadata table exists
-	var q = "SELECT 1 FROM information_schema.tables WHERE table_schema = '" + metadataTableS + "' AND table_name = '" + metadataTableT + "';"
+	var q = "SELECT 1 FROM information_schema.tables WHERE table_schema = '" + metadataTableS + "' AND table_name = '" + metadataTableT + mode.Suffix + "';"
 	var i int64
 	err = dc.QueryRow(context.TODO(), q).Scan(&i)
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -44,11 +48,51 @@ func IncUpdateAvail(dc *pgx.Conn) (bool, error) {
 	if v != schemaVersion {
 		return false, nil
 	}
+	// check if record selection or output options have changed
+	q = "SELECT config FROM " + metadataTable + " LIMIT 1;"
+	var c string
+	if err = dc.QueryRow(context.TODO(), q).Scan(&c); err != nil {
+		return false, err
+	}
+	if c != config {
+		return false, nil
+	}
 	return true, nil
 }
 
-func CreateCksum(dbc *util.DBC, srsRecords, srsMarc, srsMarctab, srsMarcAttr string) error {
+// Config returns the config string stored by the previous full update of a
+// record type, or "" if there is none.
+func Config(dc *pgx.Conn, mode *util.Mode) (string, error) {
+	var q = "SELECT 1 FROM information_schema.tables WHERE table_schema = '" + metadataTableS + "' AND table_name = '" + metadataTableT + mode.Suffix + "';"
+	var i int64
+	err := dc.QueryRow(context.TODO(), q).Scan(&i)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	q = "SELECT config FROM " + mode.MetadataTable() + " LIMIT 1;"
+	var c string
+	err = dc.QueryRow(context.TODO(), q).Scan(&c)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return c, nil
+}
+
+// CreateCksum creates the checksum and metadata tables after a full update.
+// If fieldCksum is true, the field checksum table is also created.  If ignore
+// is not nil, the checksum table also contains a checksum of the content of
+// each record, not including the ignored fields.
+func CreateCksum(dbc *util.DBC, srsRecords, srsMarc, srsMarctab, srsMarcAttr string, mode *util.Mode,
+	filter *srs.Filter, fieldCksum bool, ignore *CksumIgnore, config string) error {
 	var err error
+	cksumTable := mode.CksumTable()
+	metadataTable := mode.MetadataTable()
 	var tx pgx.Tx
 	if tx, err = util.BeginTx(context.TODO(), dbc.Conn); err != nil {
 		return err
@@ -59,29 +103,51 @@ func CreateCksum(dbc *util.DBC, srsRecords, srsMarc, srsMarctab, srsMarcAttr str
 	if _, err = tx.Exec(context.TODO(), q); err != nil {
 		return fmt.Errorf("dropping checksum table: %s", err)
 	}
-	// Filter should match srs.getInstanceID()
-	q = "CREATE TABLE " + cksumTable + " (id uuid NOT NULL,cksum text)"
+	q = "CREATE TABLE " + cksumTable + " (id uuid NOT NULL,cksum text"
+	if ignore != nil {
+		q += ",content_cksum text"
+	}
+	q += ")"
 	if _, err = tx.Exec(context.TODO(), q); err != nil {
 		return fmt.Errorf("creating checksum table: %s", err)
 	}
+	// The filter matches srs.Transform.
 	q = "INSERT INTO " + cksumTable + " (id,cksum)" +
 		" SELECT r.id::uuid, " + util.MD5(srsMarcAttr) + " cksum FROM " +
-		srsRecords + " r JOIN " + srsMarc + " m ON r.id = m.id JOIN " +
-		srsMarctab + " mt ON r.id::uuid = mt.srs_id WHERE r.state = 'ACTUAL' AND mt.field = '999' AND mt.sf = 'i' AND ind1 = 'f' AND ind2 = 'f' AND mt.content <> ''"
+		srsRecords + " r JOIN " + srsMarc + " m ON r.id = m.id WHERE " + filter.RecordSQL() +
+		" AND EXISTS (SELECT 1 FROM " + srsMarctab + " mt WHERE mt.srs_id = r.id::uuid AND " + filter.IDSQL() + ")"
 	if _, err = tx.Exec(context.TODO(), q); err != nil {
 		return fmt.Errorf("writing data to checksum table: %s", err)
 	}
-	q = "ALTER TABLE " + cksumTable + " ADD CONSTRAINT cksum_pkey PRIMARY KEY (id)"
+	q = "ALTER TABLE " + cksumTable + " ADD CONSTRAINT cksum" + mode.Suffix + "_pkey PRIMARY KEY (id)"
 	if _, err = tx.Exec(context.TODO(), q); err != nil {
 		return fmt.Errorf("indexing checksum table: %s", err)
 	}
+	if ignore != nil {
+		q = "UPDATE " + cksumTable + " c SET content_cksum = s.cksum FROM" +
+			" (SELECT srs_id, " + ignore.cksumSQL(mode, filter) + " cksum FROM " + srsMarctab + " GROUP BY srs_id) s" +
+			" WHERE c.id = s.srs_id"
+		if _, err = tx.Exec(context.TODO(), q); err != nil {
+			return fmt.Errorf("writing content checksums: %s", err)
+		}
+	}
+	// field cksum
+	q = "DROP TABLE IF EXISTS " + mode.FieldCksumTable()
+	if _, err = tx.Exec(context.TODO(), q); err != nil {
+		return fmt.Errorf("dropping field checksum table: %s", err)
+	}
+	if fieldCksum {
+		if err = createFieldCksum(context.TODO(), tx, srsMarctab, mode); err != nil {
+			return err
+		}
+	}
 	// metadata
 	q = "DROP TABLE IF EXISTS " + metadataTable
 	if _, err = tx.Exec(context.TODO(), q); err != nil {
 		return fmt.Errorf("dropping metadata table: %s", err)
 	}
-	q = "CREATE TABLE " + metadataTable + " AS SELECT " + strconv.FormatInt(schemaVersion, 10) + " AS version;"
-	if _, err = tx.Exec(context.TODO(), q); err != nil {
+	q = "CREATE TABLE " + metadataTable + " AS SELECT " + strconv.FormatInt(schemaVersion, 10) + " AS version, $1::text AS config;"
+	if _, err = tx.Exec(context.TODO(), q, config); err != nil {
 		return fmt.Errorf("creating metadata table: %s", err)
 	}
 	// commit
@@ -91,15 +157,27 @@ func CreateCksum(dbc *util.DBC, srsRecords, srsMarc, srsMarctab, srsMarcAttr str
 	return nil
 }
 
-func VacuumCksum(ctx context.Context, dbc *util.DBC) error {
+func VacuumCksum(ctx context.Context, dbc *util.DBC, mode *util.Mode, fieldCksum bool) error {
 	var err error
-	if err = util.Vacuum(ctx, dbc, cksumTable); err != nil {
+	if err = util.Vacuum(ctx, dbc, mode.CksumTable()); err != nil {
 		return err
 	}
+	if fieldCksum {
+		if err = util.Vacuum(ctx, dbc, mode.FieldCksumTable()); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func IncrementalUpdate(connString string, srsRecords, srsMarc, srsMarcAttr, tablefinal string,
+// IncrementalUpdate updates the output of a previous full update with records
+// that have been added, deleted, or changed since.  If fieldCksum is true,
+// only the fields of a changed record whose checksums differ are rewritten.
+// If ignore is not nil, a record is rewritten only if its
+// content other than the ignored fields has changed.
+func IncrementalUpdate(connString string, srsRecords, srsMarc, srsMarcAttr, tablefinal string, mode *util.Mode,
+	parser *srs.Parser, filter *srs.Filter, content *util.Content, fieldCksum bool, ignore *CksumIgnore,
+	tables []*derived.Table,
 	printerr func(string, ...any), verbose int) error {
 
 	var err error
@@ -117,17 +195,19 @@ func IncrementalUpdate(connString string, srsRecords, srsMarc, srsMarcAttr, tabl
 	defer cancel()
 	// Vacuum in case previous run was not completed.
 	_ = util.Vacuum(ctx, dbc, tablefinal)
-	_ = VacuumCksum(ctx, dbc)
+	_ = VacuumCksum(ctx, dbc, mode, fieldCksum)
 	// add new data
-	if err = updateNew(ctx, dbc, srsRecords, srsMarc, srsMarcAttr, tablefinal, printerr, verbose); err != nil {
+	if err = updateNew(ctx, dbc, srsRecords, srsMarc, srsMarcAttr, tablefinal, mode, parser, filter, content, fieldCksum, ignore,
+		tables, printerr, verbose); err != nil {
 		return fmt.Errorf("new: %s", err)
 	}
 	// remove deleted data
-	if err = updateDelete(ctx, dbc, srsRecords, tablefinal, printerr, verbose); err != nil {
+	if err = updateDelete(ctx, dbc, srsRecords, tablefinal, mode, fieldCksum, tables, printerr, verbose); err != nil {
 		return fmt.Errorf("delete: %s", err)
 	}
 	// replace modified data
-	if err = updateChange(ctx, dbc, srsRecords, srsMarc, srsMarcAttr, tablefinal, printerr, verbose); err != nil {
+	if err = updateChange(ctx, dbc, srsRecords, srsMarc, srsMarcAttr, tablefinal, mode, parser, filter, content, fieldCksum, ignore,
+		tables, printerr, verbose); err != nil {
 		return fmt.Errorf("change: %s", err)
 	}
 	// vacuum
@@ -135,9 +215,16 @@ func IncrementalUpdate(connString string, srsRecords, srsMarc, srsMarcAttr, tabl
 	if err = util.Vacuum(ctx, dbc, tablefinal); err != nil {
 		return fmt.Errorf("vacuum: %s", err)
 	}
-	if err = VacuumCksum(ctx, dbc); err != nil {
+	if err = VacuumCksum(ctx, dbc, mode, fieldCksum); err != nil {
 		return fmt.Errorf("vacuum cksum: %s", err)
 	}
+	for _, t := range tables {
+		if t.Report == nil {
+			if err = util.Vacuum(ctx, dbc, t.Final()); err != nil {
+				return fmt.Errorf("vacuum: %s", err)
+			}
+		}
+	}
 	if verbose >= 1 {
 		printerr(" %s vacuum", util.ElapsedTime(startVacuum))
 	}
@@ -147,22 +234,25 @@ func IncrementalUpdate(connString string, srsRecords, srsMarc, srsMarcAttr, tabl
 	return nil
 }
 
-func updateNew(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMarcAttr, tablefinal string, printerr func(string, ...any),
-	verbose int) error {
+func updateNew(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMarcAttr, tablefinal string, mode *util.Mode,
+	parser *srs.Parser, filter *srs.Filter, content *util.Content, fieldCksum bool, ignore *CksumIgnore, tables []*derived.Table,
+	printerr func(string, ...any), verbose int) error {
 	startNew := time.Now()
 	var err error
+	cksumTable := mode.CksumTable()
+	incAdd := "marctab.inc_add" + mode.Suffix
 	// find new data
-	_, _ = dbc.Conn.Exec(ctx, "DROP TABLE IF EXISTS marctab.inc_add")
-	var q = "CREATE UNLOGGED TABLE marctab.inc_add AS SELECT r.id::uuid FROM " + srsRecords + " r LEFT JOIN " +
-		cksumTable + " c ON r.id::uuid = c.id WHERE c.id IS NULL;"
+	_, _ = dbc.Conn.Exec(ctx, "DROP TABLE IF EXISTS "+incAdd)
+	var q = "CREATE UNLOGGED TABLE " + incAdd + " AS SELECT r.id::uuid FROM " + srsRecords + " r LEFT JOIN " +
+		cksumTable + " c ON r.id::uuid = c.id WHERE c.id IS NULL AND " + filter.RecordSQL() + ";"
 	if _, err = dbc.Conn.Exec(ctx, q); err != nil {
 		return fmt.Errorf("creating addition table: %s", err)
 	}
-	q = "ALTER TABLE marctab.inc_add ADD CONSTRAINT marctab_add_pkey PRIMARY KEY (id);"
+	q = "ALTER TABLE " + incAdd + " ADD CONSTRAINT marctab_add" + mode.Suffix + "_pkey PRIMARY KEY (id);"
 	if _, err = dbc.Conn.Exec(ctx, q); err != nil {
 		return fmt.Errorf("creating primary key on addition table: %s", err)
 	}
-	if err = util.VacuumAnalyze(ctx, dbc, "marctab.inc_add"); err != nil {
+	if err = util.VacuumAnalyze(ctx, dbc, incAdd); err != nil {
 		return fmt.Errorf("vacuum analyze: %s", err)
 	}
 	var connw *pgx.Conn
@@ -175,24 +265,26 @@ func updateNew(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMarcA
 		return err
 	}
 	defer tx.Rollback(ctx)
+	dw := derived.NewWriter(tx, tables, false)
 	// transform
-	q = filterQuery(srsRecords, srsMarc, srsMarcAttr, "marctab.inc_add")
+	q = filterQuery(srsRecords, srsMarc, srsMarcAttr, incAdd)
 	var rows pgx.Rows
 	if rows, err = dbc.Conn.Query(ctx, q); err != nil {
 		return fmt.Errorf("selecting records to add: %v", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var id, matchedID, instanceHRID, state, data *string
+		var id, matchedID, instanceHRID, state, recordType, data *string
 		var cksum string
-		if err = rows.Scan(&id, &matchedID, &instanceHRID, &state, &data, &cksum); err != nil {
+		if err = rows.Scan(&id, &matchedID, &instanceHRID, &state, &recordType, &data, &cksum); err != nil {
 			return err
 		}
 		var instanceID string
 		var mrecs []srs.Marc
+		var anomalies []srs.Anomaly
 		var skip bool
-		id, matchedID, instanceHRID, instanceID, mrecs, skip = util.Transform(id, matchedID, instanceHRID,
-			state, data, printerr, verbose)
+		id, matchedID, instanceHRID, instanceID, mrecs, anomalies, skip = util.Transform(id, matchedID, instanceHRID,
+			state, recordType, data, parser, filter, content, printerr, verbose)
 		if skip {
 			continue
 		}
@@ -200,31 +292,38 @@ func updateNew(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMarcA
 			printerr("id=%s: encoding instance_id %q: %v", *id, instanceID, err)
 			instanceID = uuid.NilUUID
 		}
-		var m srs.Marc
-		for _, m = range mrecs {
-			q = "INSERT INTO " + tablefinal + " VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)"
-			_, err = tx.Exec(ctx, q, id, m.Line, matchedID, instanceHRID, instanceID, m.Field, m.Ind1,
-				m.Ind2, m.Ord, m.SF, m.Content)
+		if err = insertRows(ctx, tx, tablefinal, filter, content, id, matchedID, instanceHRID, instanceID, state, mrecs); err != nil {
+			return fmt.Errorf("adding record: %v", err)
+		}
+		if len(mrecs) != 0 {
+			err = dw.Write(ctx, &derived.Record{SRSID: *id, InstanceID: instanceID, Leader: parser.Leader(), Marc: mrecs, Anomalies: anomalies})
 			if err != nil {
-				return fmt.Errorf("adding record: %v", err)
+				return err
 			}
 		}
 		// cksum
 		if len(mrecs) != 0 {
-			q = "INSERT INTO " + cksumTable + " VALUES($1,$2)"
-			if _, err = tx.Exec(ctx, q, id, cksum); err != nil {
+			if err = insertCksum(ctx, tx, mode, filter, ignore, id, matchedID, instanceHRID, instanceID, state, mrecs, cksum); err != nil {
 				return fmt.Errorf("adding checksum: %v", err)
 			}
+			if fieldCksum {
+				if err = writeFieldCksums(ctx, tx, mode, *id, fieldCksums(mrecs)); err != nil {
+					return err
+				}
+			}
 		}
 	}
 	if err = rows.Err(); err != nil {
 		return err
 	}
 	rows.Close()
+	if err = dw.Flush(ctx); err != nil {
+		return err
+	}
 	if err = tx.Commit(context.TODO()); err != nil {
 		return err
 	}
-	if _, err = dbc.Conn.Exec(context.TODO(), "DROP TABLE IF EXISTS marctab.inc_add"); err != nil {
+	if _, err = dbc.Conn.Exec(context.TODO(), "DROP TABLE IF EXISTS "+incAdd); err != nil {
 		return fmt.Errorf("dropping addition table: %s", err)
 	}
 	if verbose >= 1 {
@@ -233,26 +332,29 @@ func updateNew(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMarcA
 	return nil
 }
 
-func updateDelete(ctx context.Context, dbc *util.DBC, srsRecords, tablefinal string, printerr func(string, ...any), verbose int) error {
+func updateDelete(ctx context.Context, dbc *util.DBC, srsRecords, tablefinal string, mode *util.Mode, fieldCksum bool,
+	tables []*derived.Table, printerr func(string, ...any), verbose int) error {
 	startDelete := time.Now()
 	var err error
+	cksumTable := mode.CksumTable()
+	incDelete := "marctab.inc_delete" + mode.Suffix
 	// find deleted data
-	_, _ = dbc.Conn.Exec(ctx, "DROP TABLE IF EXISTS marctab.inc_delete")
-	q := "CREATE UNLOGGED TABLE marctab.inc_delete AS SELECT c.id FROM " + srsRecords + " r RIGHT JOIN " +
+	_, _ = dbc.Conn.Exec(ctx, "DROP TABLE IF EXISTS "+incDelete)
+	q := "CREATE UNLOGGED TABLE " + incDelete + " AS SELECT c.id FROM " + srsRecords + " r RIGHT JOIN " +
 		cksumTable + " c ON r.id::uuid = c.id WHERE r.id IS NULL;"
 	if _, err = dbc.Conn.Exec(ctx, q); err != nil {
 		return fmt.Errorf("creating deletion table: %s", err)
 	}
-	q = "ALTER TABLE marctab.inc_delete ADD CONSTRAINT marctab_delete_pkey PRIMARY KEY (id);"
+	q = "ALTER TABLE " + incDelete + " ADD CONSTRAINT marctab_delete" + mode.Suffix + "_pkey PRIMARY KEY (id);"
 	if _, err = dbc.Conn.Exec(ctx, q); err != nil {
 		return fmt.Errorf("creating primary key on deletion table: %s", err)
 	}
-	if err = util.VacuumAnalyze(ctx, dbc, "marctab.inc_delete"); err != nil {
+	if err = util.VacuumAnalyze(ctx, dbc, incDelete); err != nil {
 		return fmt.Errorf("vacuum analyze: %s", err)
 	}
 	if verbose >= 2 {
 		// show changes
-		q = "SELECT id FROM marctab.inc_delete;"
+		q = "SELECT id FROM " + incDelete + ";"
 		var rows pgx.Rows
 		if rows, err = dbc.Conn.Query(ctx, q); err != nil {
 			return fmt.Errorf("reading deletion list: %s", err)
@@ -283,19 +385,35 @@ func updateDelete(ctx context.Context, dbc *util.DBC, srsRecords, tablefinal str
 	}
 	defer tx.Rollback(ctx)
 	// delete in finaltable
-	q = "DELETE FROM " + tablefinal + " WHERE srs_id IN (SELECT id FROM marctab.inc_delete);"
+	q = "DELETE FROM " + tablefinal + " WHERE srs_id IN (SELECT id FROM " + incDelete + ");"
 	if _, err = tx.Exec(ctx, q); err != nil {
 		return fmt.Errorf("deleting records: %s", err)
 	}
+	// delete in derived tables
+	for _, t := range tables {
+		if t.Report != nil {
+			continue
+		}
+		q = "DELETE FROM " + t.Final() + " WHERE srs_id IN (SELECT id FROM " + incDelete + ");"
+		if _, err = tx.Exec(ctx, q); err != nil {
+			return fmt.Errorf("deleting derived records: %s: %s", t.Name, err)
+		}
+	}
 	// delete in cksum table
-	q = "DELETE FROM " + cksumTable + " WHERE id IN (SELECT id FROM marctab.inc_delete);"
+	q = "DELETE FROM " + cksumTable + " WHERE id IN (SELECT id FROM " + incDelete + ");"
 	if _, err = tx.Exec(ctx, q); err != nil {
 		return fmt.Errorf("deleting cksum: %s", err)
 	}
+	if fieldCksum {
+		q = "DELETE FROM " + mode.FieldCksumTable() + " WHERE id IN (SELECT id FROM " + incDelete + ");"
+		if _, err = tx.Exec(ctx, q); err != nil {
+			return fmt.Errorf("deleting field cksum: %s", err)
+		}
+	}
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("committing updates: %v", err)
 	}
-	if _, err = dbc.Conn.Exec(ctx, "DROP TABLE IF EXISTS marctab.inc_delete"); err != nil {
+	if _, err = dbc.Conn.Exec(ctx, "DROP TABLE IF EXISTS "+incDelete); err != nil {
 		return fmt.Errorf("dropping deletion table: %s", err)
 	}
 	if verbose >= 1 {
@@ -304,20 +422,24 @@ func updateDelete(ctx context.Context, dbc *util.DBC, srsRecords, tablefinal str
 	return nil
 }
 
-func updateChange(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMarcAttr, tablefinal string, printerr func(string, ...any), verbose int) error {
+func updateChange(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMarcAttr, tablefinal string, mode *util.Mode,
+	parser *srs.Parser, filter *srs.Filter, content *util.Content, fieldCksum bool, ignore *CksumIgnore,
+	tables []*derived.Table, printerr func(string, ...any), verbose int) error {
 	startChange := time.Now()
 	var err error
+	cksumTable := mode.CksumTable()
+	incChange := "marctab.inc_change" + mode.Suffix
 	// find changed data
-	_, _ = dbc.Conn.Exec(ctx, "DROP TABLE IF EXISTS marctab.inc_change")
-	var q = "CREATE UNLOGGED TABLE marctab.inc_change AS SELECT r.id::uuid FROM " + srsRecords + " r JOIN " + cksumTable + " c ON r.id::uuid = c.id JOIN " + srsMarc + " m ON r.id = m.id WHERE " + util.MD5(srsMarcAttr) + " <> c.cksum;"
+	_, _ = dbc.Conn.Exec(ctx, "DROP TABLE IF EXISTS "+incChange)
+	var q = "CREATE UNLOGGED TABLE " + incChange + " AS SELECT r.id::uuid FROM " + srsRecords + " r JOIN " + cksumTable + " c ON r.id::uuid = c.id JOIN " + srsMarc + " m ON r.id = m.id WHERE " + util.MD5(srsMarcAttr) + " <> c.cksum;"
 	if _, err = dbc.Conn.Exec(ctx, q); err != nil {
 		return fmt.Errorf("creating change table: %s", err)
 	}
-	q = "ALTER TABLE marctab.inc_change ADD CONSTRAINT marctab_change_pkey PRIMARY KEY (id);"
+	q = "ALTER TABLE " + incChange + " ADD CONSTRAINT marctab_change" + mode.Suffix + "_pkey PRIMARY KEY (id);"
 	if _, err = dbc.Conn.Exec(ctx, q); err != nil {
 		return fmt.Errorf("creating primary key on change table: %s", err)
 	}
-	if err = util.VacuumAnalyze(ctx, dbc, "marctab.inc_change"); err != nil {
+	if err = util.VacuumAnalyze(ctx, dbc, incChange); err != nil {
 		return fmt.Errorf("vacuum analyze: %s", err)
 	}
 	// connR is used for queries concurrent with reading rows.
@@ -337,23 +459,25 @@ func updateChange(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMa
 		return fmt.Errorf("opening transaction: %s", err)
 	}
 	defer tx.Rollback(ctx)
+	dw := derived.NewWriter(tx, tables, false)
 	// transform
-	q = filterQuery(srsRecords, srsMarc, srsMarcAttr, "marctab.inc_change")
+	q = filterQuery(srsRecords, srsMarc, srsMarcAttr, incChange)
 	var rows pgx.Rows
 	if rows, err = dbc.Conn.Query(ctx, q); err != nil {
 		return fmt.Errorf("selecting records to change: %s", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var id, matchedID, instanceHRID, state, data *string
+		var id, matchedID, instanceHRID, state, recordType, data *string
 		var cksum string
-		if err = rows.Scan(&id, &matchedID, &instanceHRID, &state, &data, &cksum); err != nil {
+		if err = rows.Scan(&id, &matchedID, &instanceHRID, &state, &recordType, &data, &cksum); err != nil {
 			return fmt.Errorf("reading changes: %s", err)
 		}
 		var instanceID string
 		var mrecs []srs.Marc
+		var anomalies []srs.Anomaly
 		var skip bool
-		id, matchedID, instanceHRID, instanceID, mrecs, skip = util.Transform(id, matchedID, instanceHRID, state, data, printerr, verbose)
+		id, matchedID, instanceHRID, instanceID, mrecs, anomalies, skip = util.Transform(id, matchedID, instanceHRID, state, recordType, data, parser, filter, content, printerr, verbose)
 		if skip {
 			continue
 		}
@@ -361,44 +485,88 @@ func updateChange(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMa
 			printerr("id=%s: encoding instance_id %q: %v", *id, instanceID, err)
 			instanceID = uuid.NilUUID
 		}
-		// check if there are existing rows in tablefinal
-		var exist bool
-		var i int64
-		q = "SELECT 1 FROM " + tablefinal + " WHERE srs_id=$1 LIMIT 1"
-		err = connR.QueryRow(ctx, q, *id).Scan(&i)
-		switch {
-		case err == pgx.ErrNoRows:
-		case err != nil:
-			return fmt.Errorf("checking for existing rows: %s", err)
-		default:
-			exist = true
-		}
-		// delete in tablefinal
-		q = "DELETE FROM " + tablefinal + " WHERE srs_id=$1"
-		if _, err = tx.Exec(ctx, q, *id); err != nil {
-			return fmt.Errorf("deleting record (change): %s", err)
+		// skip the record if only ignored fields have changed
+		if ignore != nil && len(mrecs) != 0 {
+			var unchanged bool
+			if unchanged, err = contentUnchanged(ctx, connR, mode, filter, ignore, id, matchedID, instanceHRID,
+				instanceID, state, mrecs); err != nil {
+				return err
+			}
+			if unchanged {
+				q = "UPDATE " + cksumTable + " SET cksum=$2 WHERE id=$1"
+				if _, err = tx.Exec(ctx, q, *id, cksum); err != nil {
+					return fmt.Errorf("updating checksum (change): %s", err)
+				}
+				continue
+			}
 		}
 		// delete in cksum table
 		q = "DELETE FROM " + cksumTable + " WHERE id=$1"
 		if _, err = tx.Exec(ctx, q, *id); err != nil {
 			return fmt.Errorf("deleting checksum (change): %s", err)
 		}
-		var m srs.Marc
-		for _, m = range mrecs {
-			q = "INSERT INTO " + tablefinal + " VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)"
-			_, err = tx.Exec(ctx, q, id, m.Line, matchedID, instanceHRID, instanceID, m.Field, m.Ind1,
-				m.Ind2, m.Ord, m.SF, m.Content)
+		if fieldCksum && len(mrecs) != 0 {
+			// rewrite only changed fields in tablefinal
+			var rewritten bool
+			rewritten, err = updateFields(ctx, connR, tx, tablefinal, mode, filter, content, id, matchedID, instanceHRID, instanceID, state, mrecs)
 			if err != nil {
+				return fmt.Errorf("rewriting fields (change): %s", err)
+			}
+			// rewrite derived tables if any field has changed
+			if rewritten {
+				if err = dw.Delete(ctx, *id); err != nil {
+					return err
+				}
+				err = dw.Write(ctx, &derived.Record{SRSID: *id, InstanceID: instanceID, Leader: parser.Leader(), Marc: mrecs, Anomalies: anomalies})
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			// check if there are existing rows in tablefinal
+			var exist bool
+			var i int64
+			q = "SELECT 1 FROM " + tablefinal + " WHERE srs_id=$1 LIMIT 1"
+			err = connR.QueryRow(ctx, q, *id).Scan(&i)
+			switch {
+			case err == pgx.ErrNoRows:
+			case err != nil:
+				return fmt.Errorf("checking for existing rows: %s", err)
+			default:
+				exist = true
+			}
+			// delete in tablefinal
+			q = "DELETE FROM " + tablefinal + " WHERE srs_id=$1"
+			if _, err = tx.Exec(ctx, q, *id); err != nil {
+				return fmt.Errorf("deleting record (change): %s", err)
+			}
+			// delete in field cksum table
+			if fieldCksum {
+				q = "DELETE FROM " + mode.FieldCksumTable() + " WHERE id=$1"
+				if _, err = tx.Exec(ctx, q, *id); err != nil {
+					return fmt.Errorf("deleting field checksums (change): %s", err)
+				}
+			}
+			// delete in derived tables
+			if err = dw.Delete(ctx, *id); err != nil {
+				return err
+			}
+			if err = insertRows(ctx, tx, tablefinal, filter, content, id, matchedID, instanceHRID, instanceID, state, mrecs); err != nil {
 				return fmt.Errorf("rewriting record: %s", err)
 			}
-		}
-		if verbose >= 2 && exist && len(mrecs) == 0 {
-			printerr("removing: id=%s", *id)
+			if len(mrecs) != 0 {
+				err = dw.Write(ctx, &derived.Record{SRSID: *id, InstanceID: instanceID, Leader: parser.Leader(), Marc: mrecs, Anomalies: anomalies})
+				if err != nil {
+					return err
+				}
+			}
+			if verbose >= 2 && exist && len(mrecs) == 0 {
+				printerr("removing: id=%s", *id)
+			}
 		}
 		// cksum
 		if len(mrecs) != 0 {
-			q = "INSERT INTO " + cksumTable + " VALUES($1,$2)"
-			if _, err = tx.Exec(ctx, q, id, cksum); err != nil {
+			if err = insertCksum(ctx, tx, mode, filter, ignore, id, matchedID, instanceHRID, instanceID, state, mrecs, cksum); err != nil {
 				return fmt.Errorf("rewriting checksum: %s", err)
 			}
 		}
@@ -407,10 +575,13 @@ func updateChange(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMa
 		return err
 	}
 	rows.Close()
+	if err = dw.Flush(ctx); err != nil {
+		return err
+	}
 	if err = tx.Commit(ctx); err != nil {
 		return err
 	}
-	if _, err = dbc.Conn.Exec(ctx, "DROP TABLE IF EXISTS marctab.inc_change"); err != nil {
+	if _, err = dbc.Conn.Exec(ctx, "DROP TABLE IF EXISTS "+incChange); err != nil {
 		return fmt.Errorf("dropping change table: %s", err)
 	}
 	if verbose >= 1 {
@@ -419,10 +590,73 @@ func updateChange(ctx context.Context, dbc *util.DBC, srsRecords, srsMarc, srsMa
 	return nil
 }
 
+// insertRows writes the transformed rows of a record to tablefinal.  A
+// content_folded column is written if enabled, and a state column is written
+// if the filter may select records that are not current.
+func insertRows(ctx context.Context, tx pgx.Tx, tablefinal string, filter *srs.Filter, content *util.Content,
+	id, matchedID, instanceHRID *string, instanceID string, state *string, mrecs []srs.Marc) error {
+	n := 11
+	if content.Folded {
+		n++
+	}
+	if filter.NonActual() {
+		n++
+	}
+	q := "INSERT INTO " + tablefinal + " VALUES($1"
+	for i := 2; i <= n; i++ {
+		q += ",$" + strconv.Itoa(i)
+	}
+	q += ")"
+	var m srs.Marc
+	for _, m = range mrecs {
+		args := []any{id, m.Line, matchedID, instanceHRID, instanceID, m.Field, m.Ind1, m.Ind2, m.Ord, m.SF, m.Content}
+		if content.Folded {
+			args = append(args, textnorm.Fold(m.Content))
+		}
+		if filter.NonActual() {
+			args = append(args, state)
+		}
+		if _, err := tx.Exec(ctx, q, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func filterQuery(srsRecords, srsMarc, srsMarcAttr, filter string) string {
 	return "" +
-		"SELECT r.id::uuid, r.matched_id::uuid, r.external_hrid instance_hrid, r.state, m." + srsMarcAttr + "::text, " + util.MD5(srsMarcAttr) + " cksum " +
+		"SELECT r.id::uuid, r.matched_id::uuid, r.external_hrid instance_hrid, r.state, r.record_type::text, m." + srsMarcAttr + "::text, " + util.MD5(srsMarcAttr) + " cksum " +
 		"    FROM " + srsRecords + " r " +
 		"        JOIN " + filter + " f ON r.id::uuid = f.id " +
 		"        JOIN " + srsMarc + " m ON r.id = m.id;"
 }
+
+// insertCksum writes the checksum of a record, and its content checksum if
+// ignore is not nil.
+func insertCksum(ctx context.Context, tx pgx.Tx, mode *util.Mode, filter *srs.Filter, ignore *CksumIgnore,
+	id, matchedID, instanceHRID *string, instanceID string, state *string, mrecs []srs.Marc, cksum string) error {
+	if ignore == nil {
+		q := "INSERT INTO " + mode.CksumTable() + " VALUES($1,$2)"
+		_, err := tx.Exec(ctx, q, id, cksum)
+		return err
+	}
+	q := "INSERT INTO " + mode.CksumTable() + " VALUES($1,$2,$3)"
+	_, err := tx.Exec(ctx, q, id, cksum, ignore.cksum(filter, *matchedID, *instanceHRID, instanceID, *state, mrecs))
+	return err
+}
+
+// contentUnchanged reports whether the content checksum of a record, which
+// does not include the ignored fields, is equal to the stored one.
+func contentUnchanged(ctx context.Context, connR *pgx.Conn, mode *util.Mode, filter *srs.Filter, ignore *CksumIgnore,
+	id, matchedID, instanceHRID *string, instanceID string, state *string, mrecs []srs.Marc) (bool, error) {
+	var old *string
+	q := "SELECT content_cksum FROM " + mode.CksumTable() + " WHERE id=$1"
+	err := connR.QueryRow(ctx, q, *id).Scan(&old)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("reading content checksum: %s", err)
+	}
+	return old != nil && *old == ignore.cksum(filter, *matchedID, *instanceHRID, instanceID, *state, mrecs), nil
+}
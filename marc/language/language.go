@@ -0,0 +1,143 @@
+// Package language extracts language codes from the 008 and 041 fields of
+// transformed SRS MARC records and validates them against the MARC code
+// list for languages.
+package language
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/library-data-platform/ldpmarc/marc/fixed"
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+// MARC is the source of codes defined in the MARC code list for languages.
+const MARC = "marc"
+
+// Language is a language code found in a record.
+type Language struct {
+	// Field is "008" or "041".
+	Field string
+	Ord   int16
+	// SF is the 041 subfield code, e.g. "a" for the language of the text
+	// or "h" for the original language, or "" for 008.
+	SF   string
+	Code string
+	// Source is MARC, or for 041 with second indicator 7, the source of
+	// the code given in $2.
+	Source string
+	// Name is the name of the language, or "" if the code is not defined.
+	Name string
+	// Valid reports whether the code is defined in the MARC code list,
+	// including obsolete codes.  It is always false for codes from other
+	// sources.
+	Valid bool
+	// Obsolete reports whether the code is obsolete.
+	Obsolete bool
+}
+
+type entry struct {
+	name     string
+	obsolete bool
+}
+
+//go:embed languages.txt
+var languagesText string
+
+var languages = parseLanguages(languagesText)
+
+func parseLanguages(text string) map[string]entry {
+	m := make(map[string]entry)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, name, ok := strings.Cut(line, " ")
+		obsolete := strings.HasPrefix(code, "-")
+		code = strings.TrimPrefix(code, "-")
+		if !ok || len(code) != 3 {
+			panic("invalid language: " + line)
+		}
+		m[code] = entry{name: strings.TrimSpace(name), obsolete: obsolete}
+	}
+	return m
+}
+
+// Lookup returns the name of a MARC language code and reports whether the
+// code is defined and whether it is obsolete.
+func Lookup(code string) (string, bool, bool) {
+	e, ok := languages[code]
+	return e.name, ok, e.obsolete
+}
+
+// Extract returns the language codes in 008/35-37 and the subfields of 041
+// in a transformed record.  Older records may contain several codes run
+// together in a single 041 subfield, e.g. "engfre", which are returned
+// separately.
+func Extract(mrecs []srs.Marc) []Language {
+	var langs []Language
+	var f008 bool
+	for _, sfs := range srs.Fields(mrecs) {
+		m := sfs[0]
+		switch m.Field {
+		case "008":
+			code, ok := fixed.Slice(m.Content, 35, 38)
+			if f008 || !ok {
+				continue
+			}
+			f008 = true
+			code = strings.ToLower(code)
+			if strings.TrimSpace(strings.Trim(code, "|")) == "" || !utf8.ValidString(code) {
+				continue
+			}
+			langs = append(langs, newLanguage(m.Field, m.Ord, "", code, MARC))
+		case "041":
+			source := MARC
+			if m.Ind2 == "7" {
+				source = ""
+				for _, sf := range sfs {
+					if sf.SF == "2" {
+						source = strings.TrimSpace(sf.Content)
+						break
+					}
+				}
+			}
+			for _, sf := range sfs {
+				v := strings.ToLower(strings.TrimSpace(sf.Content))
+				if v == "" || sf.SF == "" || sf.SF == "2" || sf.SF == "3" || sf.SF == "6" || sf.SF == "8" {
+					continue
+				}
+				for _, code := range split(v, source) {
+					langs = append(langs, newLanguage(m.Field, m.Ord, sf.SF, code, source))
+				}
+			}
+		}
+	}
+	return langs
+}
+
+func newLanguage(field string, ord int16, sf, code, source string) Language {
+	l := Language{Field: field, Ord: ord, SF: sf, Code: code, Source: source}
+	if source == MARC {
+		l.Name, l.Valid, l.Obsolete = Lookup(code)
+	}
+	return l
+}
+
+// split separates MARC codes that are run together in a subfield.  Only a
+// value consisting of ASCII letters, whose length is a multiple of 3, is
+// split; any other value is returned whole, and is not a valid code.
+func split(v, source string) []string {
+	if source != MARC || len(v) <= 3 || len(v)%3 != 0 || strings.Trim(v, "abcdefghijklmnopqrstuvwxyz") != "" {
+		return []string{v}
+	}
+	codes := make([]string, 0, len(v)/3)
+	for k := 0; k < len(v); k += 3 {
+		codes = append(codes, v[k:k+3])
+	}
+	return codes
+}
@@ -0,0 +1,59 @@
+package language
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name  string
+		mrecs []srs.Marc
+		want  []Language
+	}{
+		{"008 and 041", []srs.Marc{
+			{Field: "008", Ord: 1, Content: "980101s1998    nyu           000 0 eng d"},
+			{Field: "041", Ord: 1, Ind1: "1", Ind2: " ", SF: "a", Content: "eng"},
+			{Field: "041", Ord: 1, Ind1: "1", Ind2: " ", SF: "h", Content: "FRE"},
+		}, []Language{
+			{Field: "008", Ord: 1, Code: "eng", Source: MARC, Name: "English", Valid: true},
+			{Field: "041", Ord: 1, SF: "a", Code: "eng", Source: MARC, Name: "English", Valid: true},
+			{Field: "041", Ord: 1, SF: "h", Code: "fre", Source: MARC, Name: "French", Valid: true},
+		}},
+		{"run together", []srs.Marc{
+			{Field: "041", Ord: 1, Ind1: "0", Ind2: " ", SF: "a", Content: "engesk"},
+		}, []Language{
+			{Field: "041", Ord: 1, SF: "a", Code: "eng", Source: MARC, Name: "English", Valid: true},
+			{Field: "041", Ord: 1, SF: "a", Code: "esk", Source: MARC, Name: "Eskimo languages", Valid: true,
+				Obsolete: true},
+		}},
+		{"not split", []srs.Marc{
+			{Field: "041", Ord: 1, Ind1: "0", Ind2: " ", SF: "a", Content: "abñde"},
+			{Field: "041", Ord: 1, Ind1: "0", Ind2: " ", SF: "b", Content: "eng fre"},
+		}, []Language{
+			{Field: "041", Ord: 1, SF: "a", Code: "abñde", Source: MARC},
+			{Field: "041", Ord: 1, SF: "b", Code: "eng fre", Source: MARC},
+		}},
+		{"other source", []srs.Marc{
+			{Field: "041", Ord: 1, Ind1: "0", Ind2: "7", SF: "a", Content: "enfr"},
+			{Field: "041", Ord: 1, Ind1: "0", Ind2: "7", SF: "2", Content: "iso639-1"},
+		}, []Language{
+			{Field: "041", Ord: 1, SF: "a", Code: "enfr", Source: "iso639-1"},
+		}},
+		{"non-ASCII 008", []srs.Marc{
+			{Field: "008", Ord: 1, Content: "9801ñ1s1998    xx            000 0 fré d"},
+		}, []Language{
+			{Field: "008", Ord: 1, Code: "fré", Source: MARC},
+		}},
+		{"blank 008", []srs.Marc{
+			{Field: "008", Ord: 1, Content: "980101s1998    nyu           000 0 ||| d"},
+		}, nil},
+	}
+	for _, tt := range tests {
+		if got := Extract(tt.mrecs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Extract() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package link extracts electronic access links (856) from transformed SRS
+// MARC records and checks them against a list of allowed and denied hosts.
+package link
+
+import (
+	"bufio"
+	"crypto/md5"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+// Link is a URL found in 856 $u.
+type Link struct {
+	Ord  int16
+	Ind2 string
+	// Relationship is the relationship of the resource to the item
+	// described, from the second indicator.
+	Relationship string
+	URL          string
+	// LinkText is $y, PublicNote is $z, and Materials is $3.
+	LinkText   string
+	PublicNote string
+	Materials  string
+	// Host is the host name in the URL, lowercased and without a port.
+	Host string
+	// Valid reports whether the URL is syntactically valid.
+	Valid bool
+}
+
+// relationships maps the second indicator of 856 to a relationship.
+var relationships = map[string]string{
+	" ": "no information provided",
+	"0": "resource",
+	"1": "version of resource",
+	"2": "related resource",
+	"8": "no display constant generated",
+}
+
+// Extract returns the links in the 856 fields of a transformed record, one for
+// each $u.
+func Extract(mrecs []srs.Marc) []Link {
+	var links []Link
+	for _, sfs := range srs.Fields(mrecs) {
+		m := sfs[0]
+		if m.Field != "856" {
+			continue
+		}
+		var l Link
+		l.Ord = m.Ord
+		l.Ind2 = m.Ind2
+		l.Relationship = relationships[m.Ind2]
+		var urls []string
+		for _, sf := range sfs {
+			v := strings.TrimSpace(sf.Content)
+			switch sf.SF {
+			case "u":
+				if v != "" {
+					urls = append(urls, v)
+				}
+			case "y":
+				l.LinkText = first(l.LinkText, v)
+			case "z":
+				l.PublicNote = first(l.PublicNote, v)
+			case "3":
+				l.Materials = first(l.Materials, v)
+			}
+		}
+		for _, u := range urls {
+			l.URL = u
+			l.Host, l.Valid = Parse(u)
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+func first(s, v string) string {
+	if s != "" {
+		return s
+	}
+	return v
+}
+
+// schemes lists the URL schemes accepted as valid.
+var schemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"ftp":    true,
+	"ftps":   true,
+	"gopher": true,
+	"telnet": true,
+}
+
+// Parse returns the host of a URL and reports whether the URL is
+// syntactically valid, i.e. it contains no blanks and has a recognized
+// scheme and a host.
+func Parse(s string) (string, bool) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	valid := !strings.ContainsAny(s, " \t") && schemes[strings.ToLower(u.Scheme)] && host != "" &&
+		!strings.ContainsAny(host, "/\\") && !strings.HasPrefix(host, ".") && !strings.HasSuffix(host, ".")
+	return host, valid
+}
+
+// Link check statuses.
+const (
+	Allowed  = "allowed"
+	Denied   = "denied"
+	Unlisted = "unlisted"
+	Invalid  = "invalid"
+)
+
+// Hosts is a list of allowed and denied host patterns.
+type Hosts struct {
+	rules []hostRule
+	text  string
+}
+
+type hostRule struct {
+	allow   bool
+	pattern string
+}
+
+// LoadHosts reads a host list from a file.  Each line contains "allow" or
+// "deny" followed by a host name, which may begin with "*." to match any
+// subdomain.  Blank lines and lines beginning with "#" are ignored.
+func LoadHosts(filename string) (*Hosts, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	h := &Hosts{text: string(data)}
+	scanner := bufio.NewScanner(strings.NewReader(h.text))
+	var n int
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) != 2 || (f[0] != "allow" && f[0] != "deny") {
+			return nil, fmt.Errorf("%s: line %d: invalid host rule: %s", filename, n, line)
+		}
+		h.rules = append(h.rules, hostRule{allow: f[0] == "allow", pattern: strings.ToLower(f[1])})
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Check returns the status of a link according to the first rule that
+// matches its host:  Allowed, Denied, or Unlisted if no rule matches.  A link
+// that is not valid has the status Invalid.
+func (h *Hosts) Check(l *Link) string {
+	if !l.Valid {
+		return Invalid
+	}
+	for _, r := range h.rules {
+		if matchHost(r.pattern, l.Host) {
+			if r.allow {
+				return Allowed
+			}
+			return Denied
+		}
+	}
+	return Unlisted
+}
+
+// Signature returns a checksum of the host list which can be compared with a
+// previous one.
+func (h *Hosts) Signature() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(h.text)))
+}
+
+func matchHost(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
@@ -0,0 +1,95 @@
+package link
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		url, host string
+		valid     bool
+	}{
+		{"https://www.example.org/book", "www.example.org", true},
+		{"HTTP://Example.ORG:8080/x?y=1", "example.org", true},
+		{"ftp://ftp.example.org/pub", "ftp.example.org", true},
+		{"https://www.example.org/a book", "www.example.org", false},
+		{"mailto:library@example.org", "", false},
+		{"www.example.org/book", "", false},
+		{"http://example.org./", "example.org.", false},
+		{"http://[::1", "", false},
+	}
+	for _, tt := range tests {
+		if host, valid := Parse(tt.url); host != tt.host || valid != tt.valid {
+			t.Errorf("Parse(%q) = %q, %v, want %q, %v", tt.url, host, valid, tt.host, tt.valid)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	mrecs := []srs.Marc{
+		{Field: "245", Ord: 1, SF: "a", Content: "Title"},
+		{Field: "856", Ord: 1, Ind1: "4", Ind2: "0", SF: "3", Content: "Full text"},
+		{Field: "856", Ord: 1, Ind1: "4", Ind2: "0", SF: "u", Content: " https://www.example.org/a "},
+		{Field: "856", Ord: 1, Ind1: "4", Ind2: "0", SF: "u", Content: "http://mirror.example.net/a"},
+		{Field: "856", Ord: 1, Ind1: "4", Ind2: "0", SF: "z", Content: "Campus access only"},
+		{Field: "856", Ord: 2, Ind1: "4", Ind2: "2", SF: "y", Content: "Cover"},
+		{Field: "856", Ord: 2, Ind1: "4", Ind2: "2", SF: "u", Content: "cover.jpg"},
+		{Field: "856", Ord: 3, Ind1: "4", Ind2: "1", SF: "z", Content: "No URL"},
+	}
+	want := []Link{
+		{Ord: 1, Ind2: "0", Relationship: "resource", URL: "https://www.example.org/a",
+			PublicNote: "Campus access only", Materials: "Full text", Host: "www.example.org", Valid: true},
+		{Ord: 1, Ind2: "0", Relationship: "resource", URL: "http://mirror.example.net/a",
+			PublicNote: "Campus access only", Materials: "Full text", Host: "mirror.example.net", Valid: true},
+		{Ord: 2, Ind2: "2", Relationship: "related resource", URL: "cover.jpg", LinkText: "Cover"},
+	}
+	if got := Extract(mrecs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "hosts.txt")
+	text := "# Rules are tried in order.\n\ndeny proxy.example.org\nallow *.example.org\nallow example.net\n"
+	if err := os.WriteFile(filename, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h, err := LoadHosts(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url, status string
+	}{
+		{"https://www.example.org/a", Allowed},
+		{"https://WWW.EXAMPLE.ORG/a", Allowed},
+		{"https://proxy.example.org/login?url=x", Denied},
+		{"https://example.org/a", Unlisted},
+		{"https://badexample.org/a", Unlisted},
+		{"http://example.net:8080/a", Allowed},
+		{"http://www.example.net/a", Unlisted},
+		{"www.example.org/a", Invalid},
+	}
+	for _, tt := range tests {
+		l := Link{URL: tt.url}
+		l.Host, l.Valid = Parse(tt.url)
+		if status := h.Check(&l); status != tt.status {
+			t.Errorf("Check(%q) = %q, want %q", tt.url, status, tt.status)
+		}
+	}
+}
+
+func TestLoadHostsInvalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "hosts.txt")
+	if err := os.WriteFile(filename, []byte("allow example.org\npermit example.net\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHosts(filename); err == nil {
+		t.Errorf("LoadHosts() accepted an invalid rule")
+	}
+}
@@ -15,23 +15,27 @@ import (
 )
 
 type Record struct {
-	SRSID        string
-	Line         int16
-	MatchedID    string
-	InstanceHRID string
-	InstanceID   string
-	Field        string
-	Ind1         string
-	Ind2         string
-	Ord          int16
-	SF           string
-	Content      string
+	SRSID         string
+	Line          int16
+	MatchedID     string
+	InstanceHRID  string
+	InstanceID    string
+	Field         string
+	Ind1          string
+	Ind2          string
+	Ord           int16
+	SF            string
+	Content       string
+	ContentFolded string
+	State         string
 }
 
 type Store struct {
 	bins        map[string]*bin
 	basepath    string
 	doneWriting bool
+	folded      bool
+	state       bool
 }
 
 type bin struct {
@@ -41,7 +45,10 @@ type bin struct {
 	path    string
 }
 
-func NewStore(datadir string) (*Store, error) {
+// NewStore creates a Store in datadir.  If folded is true, the folded content
+// is included in the values read from the store, and if state is true, the
+// record state is included.
+func NewStore(datadir string, folded, state bool) (*Store, error) {
 	var err error
 	var bins = make(map[string]*bin)
 	var allFields = util.GetAllFieldNames()
@@ -71,6 +78,8 @@ func NewStore(datadir string) (*Store, error) {
 	return &Store{
 		bins:     bins,
 		basepath: basepath,
+		folded:   folded,
+		state:    state,
 	}, nil
 }
 
@@ -128,6 +137,8 @@ type Source struct {
 	reader   *bufio.Reader
 	file     *os.File
 	path     string
+	folded   bool
+	state    bool
 	printerr func(string, ...any)
 }
 
@@ -151,6 +162,8 @@ func (s *Store) ReadSource(field string, printerr func(string, ...any)) (*Source
 		reader:   r,
 		file:     file,
 		path:     b.path,
+		folded:   s.folded,
+		state:    s.state,
 		printerr: printerr,
 	}, nil
 }
@@ -211,6 +224,12 @@ func (s *Source) Values() ([]any, error) {
 			r.SF,
 			r.Content,
 		}
+		if s.folded {
+			v = append(v, r.ContentFolded)
+		}
+		if s.state {
+			v = append(v, r.State)
+		}
 		return v, nil
 	}
 }
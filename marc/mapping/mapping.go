@@ -0,0 +1,212 @@
+// Package mapping reads a mapping configuration which defines the columns of
+// a wide output table, with one row per record, and generates rows from
+// transformed SRS MARC records.
+package mapping
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/fixed"
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"gopkg.in/yaml.v3"
+)
+
+// Mapping defines a wide output table.
+type Mapping struct {
+	// Table is the name of the table, which defaults to "wide".
+	Table   string
+	Columns []*Column
+}
+
+// Column defines a column of the table by an expression such as "245$a$b",
+// "008/35-37", "035$a where matches ^\(OCoLC\)", or "650$a (array)".
+type Column struct {
+	Name string
+	Expr string
+	tag  string
+	// subfields lists the subfield codes to be joined; if empty, the
+	// content of a control field is used.
+	subfields string
+	// start and end select character positions of a control field, if
+	// start >= 0.
+	start int
+	end   int
+	match *regexp.Regexp
+	// Array causes all values to be returned as an array, instead of only
+	// the first value.
+	Array bool
+}
+
+var namePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Load reads a mapping configuration from a YAML or JSON file.
+func Load(filename string) (*Mapping, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return m, nil
+}
+
+// Parse parses a mapping configuration in YAML or JSON format, e.g.:
+//
+//	table: wide
+//	columns:
+//	  title: 245$a$b
+//	  oclc: 035$a where matches ^\(OCoLC\)
+//	  subjects: 650$a (array)
+//
+// Columns are created in the order in which they are listed.
+func Parse(data []byte) (*Mapping, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("mapping configuration is not an object")
+	}
+	m := &Mapping{Table: "wide"}
+	top := doc.Content[0]
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		k, v := top.Content[i], top.Content[i+1]
+		switch k.Value {
+		case "table":
+			if !namePattern.MatchString(v.Value) {
+				return nil, fmt.Errorf("invalid table name %q", v.Value)
+			}
+			m.Table = v.Value
+		case "columns":
+			if v.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("\"columns\" is not an object")
+			}
+			seen := map[string]bool{"srs_id": true, "instance_id": true}
+			for j := 0; j+1 < len(v.Content); j += 2 {
+				name, expr := v.Content[j].Value, v.Content[j+1].Value
+				if !namePattern.MatchString(name) || seen[name] {
+					return nil, fmt.Errorf("invalid or duplicate column name %q", name)
+				}
+				seen[name] = true
+				c, err := parseColumn(name, expr)
+				if err != nil {
+					return nil, err
+				}
+				m.Columns = append(m.Columns, c)
+			}
+		default:
+			return nil, fmt.Errorf("unknown key %q", k.Value)
+		}
+	}
+	if len(m.Columns) == 0 {
+		return nil, fmt.Errorf("no columns defined")
+	}
+	return m, nil
+}
+
+var specPattern = regexp.MustCompile(`^(\d{3})((?:\$[0-9a-z])*)(?:/(\d{1,2})(?:-(\d{1,2}))?)?$`)
+
+func parseColumn(name, expr string) (*Column, error) {
+	c := &Column{Name: name, Expr: expr, start: -1}
+	s := strings.TrimSpace(expr)
+	if strings.HasSuffix(s, "(array)") {
+		c.Array = true
+		s = strings.TrimSpace(strings.TrimSuffix(s, "(array)"))
+	}
+	if spec, pattern, ok := strings.Cut(s, " where matches "); ok {
+		var err error
+		if c.match, err = regexp.Compile(strings.TrimSpace(pattern)); err != nil {
+			return nil, fmt.Errorf("column %q: invalid pattern: %v", name, err)
+		}
+		s = strings.TrimSpace(spec)
+	}
+	m := specPattern.FindStringSubmatch(s)
+	if m == nil || (m[2] != "" && m[3] != "") {
+		return nil, fmt.Errorf("column %q: invalid expression %q", name, expr)
+	}
+	c.tag = m[1]
+	c.subfields = strings.ReplaceAll(m[2], "$", "")
+	if m[3] != "" {
+		c.start, _ = strconv.Atoi(m[3])
+		c.end = c.start
+		if m[4] != "" {
+			c.end, _ = strconv.Atoi(m[4])
+		}
+		if c.end < c.start {
+			return nil, fmt.Errorf("column %q: invalid positions %q", name, expr)
+		}
+	}
+	return c, nil
+}
+
+// Row returns the values of the columns for a transformed record.  A scalar
+// column is the first matching value or nil, and an array column is a
+// []string or nil.
+func (m *Mapping) Row(mrecs []srs.Marc) []any {
+	row := make([]any, len(m.Columns))
+	for i, c := range m.Columns {
+		values := c.values(mrecs)
+		switch {
+		case len(values) == 0:
+			row[i] = nil
+		case c.Array:
+			row[i] = values
+		default:
+			row[i] = values[0]
+		}
+	}
+	return row
+}
+
+// values returns the matching values of the column in a record, one for each
+// field occurrence.
+func (c *Column) values(mrecs []srs.Marc) []string {
+	var values []string
+	var parts []string
+	flush := func() {
+		v := strings.Join(parts, " ")
+		parts = parts[:0]
+		if v == "" || (c.match != nil && !c.match.MatchString(v)) {
+			return
+		}
+		values = append(values, v)
+	}
+	for i, m := range mrecs {
+		if m.Field != c.tag {
+			continue
+		}
+		switch {
+		case c.subfields == "" && c.start >= 0:
+			if v, ok := fixed.Slice(m.Content, c.start, c.end+1); ok {
+				parts = append(parts, v)
+			}
+		case c.subfields == "":
+			parts = append(parts, m.Content)
+		case m.SF != "" && strings.Contains(c.subfields, m.SF):
+			if v := strings.TrimSpace(m.Content); v != "" {
+				parts = append(parts, v)
+			}
+		}
+		if i+1 == len(mrecs) || mrecs[i+1].Field != m.Field || mrecs[i+1].Ord != m.Ord {
+			flush()
+		}
+	}
+	return values
+}
+
+// Signature returns a description of the table definition which can be
+// compared with a previous one.
+func (m *Mapping) Signature() string {
+	var b strings.Builder
+	b.WriteString(m.Table)
+	for _, c := range m.Columns {
+		b.WriteString(";" + c.Name + "=" + c.Expr)
+	}
+	return b.String()
+}
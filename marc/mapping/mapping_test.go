@@ -0,0 +1,63 @@
+package mapping
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, config string
+		ok           bool
+	}{
+		{"valid", "table: books\ncolumns:\n  title: 245$a$b\n  lang: 008/35-37\n", true},
+		{"json", `{"columns": {"title": "245$a", "isbn": "020$a (array)"}}`, true},
+		{"no columns", "table: books\n", false},
+		{"invalid table", "table: Books\ncolumns:\n  title: 245$a\n", false},
+		{"duplicate column", "columns:\n  title: 245$a\n  title: 246$a\n", false},
+		{"reserved column", "columns:\n  srs_id: 001\n", false},
+		{"unknown key", "tables: books\ncolumns:\n  title: 245$a\n", false},
+		{"subfields and positions", "columns:\n  x: 008$a/35-37\n", false},
+		{"reversed positions", "columns:\n  x: 008/37-35\n", false},
+		{"invalid pattern", "columns:\n  x: 035$a where matches ((\n", false},
+	}
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.config)); (err == nil) != tt.ok {
+			t.Errorf("%s: Parse() error = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestRow(t *testing.T) {
+	m, err := Parse([]byte(`columns:
+  title: 245$a$b
+  oclc: 035$a where matches ^\(OCoLC\)
+  lang: 008/35-37
+  date_type: 008/6
+  subjects: 650$a (array)
+  isbn: 020$a (array)
+  edition: 250$a
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mrecs := []srs.Marc{
+		{Field: "008", Ord: 1, Content: "9801ñ1s1998    nyu           000 0 fré d"},
+		{Field: "035", Ord: 1, SF: "a", Content: "(DLC)   85000002"},
+		{Field: "035", Ord: 2, SF: "a", Content: "(OCoLC)12345"},
+		{Field: "035", Ord: 3, SF: "a", Content: "(OCoLC)67890"},
+		{Field: "245", Ord: 1, SF: "a", Content: "The wind in the willows :"},
+		{Field: "245", Ord: 1, SF: "c", Content: "Kenneth Grahame."},
+		{Field: "245", Ord: 1, SF: "b", Content: "a novel"},
+		{Field: "650", Ord: 1, SF: "a", Content: "Animals"},
+		{Field: "650", Ord: 1, SF: "x", Content: "Fiction."},
+		{Field: "650", Ord: 2, SF: "a", Content: "Rivers"},
+	}
+	want := []any{"The wind in the willows : a novel", "(OCoLC)12345", "fré", "s",
+		[]string{"Animals", "Rivers"}, nil, nil}
+	if got := m.Row(mrecs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Row() = %q, want %q", got, want)
+	}
+}
@@ -6,13 +6,20 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/library-data-platform/ldpmarc/marc/derived"
+	"github.com/library-data-platform/ldpmarc/marc/format"
 	"github.com/library-data-platform/ldpmarc/marc/inc"
+	"github.com/library-data-platform/ldpmarc/marc/link"
 	"github.com/library-data-platform/ldpmarc/marc/local"
+	"github.com/library-data-platform/ldpmarc/marc/mapping"
 	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/textnorm"
 	"github.com/library-data-platform/ldpmarc/marc/util"
+	"github.com/library-data-platform/ldpmarc/marc/validate"
 	"github.com/spf13/viper"
 	"gopkg.in/ini.v1"
 )
@@ -31,6 +38,104 @@ type TransformOptions struct {
 	Metadb       bool
 	PrintErr     PrintErr
 	Loc          Locations
+	// Validate enables validation of records against MARC 21 rules.
+	Validate bool
+	// ValidateReport is the name of a CSV file to which validation
+	// findings are written instead of a table.
+	ValidateReport string
+	// ValidateSeverity is a comma-separated list of validation severities
+	// to be reported.
+	ValidateSeverity string
+	// Holdings enables transformation of holdings records.
+	Holdings bool
+	// Authority enables transformation of authority records.
+	Authority bool
+	// States lists the record states to be transformed.  If empty, only
+	// records with state "ACTUAL" are transformed.
+	States []string
+	// RecordTypes lists the record types to be transformed into the
+	// bibliographic output table.  If empty, only "MARC_BIB" records are
+	// transformed.
+	RecordTypes []string
+	// IncludeNoID enables transformation of records that have no
+	// identifier in 999$i.
+	IncludeNoID bool
+	// Decode007 enables decoding of 006 and 007 fields into a separate
+	// table.
+	Decode007 bool
+	// NFC enables normalization of content to Unicode NFC.
+	NFC bool
+	// ContentFolded enables an additional content_folded column containing
+	// content lowercased, with diacritics removed and punctuation
+	// collapsed.
+	ContentFolded bool
+	// Summary enables a table containing the main bibliographic elements
+	// of each record.
+	Summary bool
+	// Names enables a table containing the personal, corporate, and
+	// meeting names in 1XX and 7XX fields.
+	Names bool
+	// Leader008 enables decoding of the leader and 008 field of
+	// bibliographic records into a separate table.
+	Leader008 bool
+	// AltGraphic enables a table linking 880 fields to the fields they
+	// parallel.
+	AltGraphic bool
+	// Identifiers enables a table containing normalized standard
+	// identifiers.
+	Identifiers bool
+	// CallNumbers enables a table containing LC and Dewey call numbers
+	// parsed into components with sort keys.
+	CallNumbers bool
+	// Subjects enables a table containing the subject headings in 6XX
+	// fields.
+	Subjects bool
+	// Dates enables a table containing a normalized publication date for
+	// each record.
+	Dates bool
+	// Languages enables a table containing the language codes in 008 and
+	// 041.
+	Languages bool
+	// FieldLinks enables a table containing the field links and sequence
+	// numbers in subfield $8, for all record types transformed.
+	FieldLinks bool
+	// Mapping is the name of a mapping configuration file defining the
+	// columns of a wide table with one row per record.
+	Mapping string
+	// MappingCSV is the name of a CSV file to which the wide table
+	// defined by Mapping is written instead of a table.
+	MappingCSV string
+	// Links enables a table containing the URLs in 856 fields.
+	Links bool
+	// LinkHosts is the name of a file listing allowed and denied hosts,
+	// against which links in 856 fields are checked.  It implies Links.
+	LinkHosts string
+	// Formats enables a table containing the format of each record.
+	Formats bool
+	// FormatRules is the name of a file containing format classification
+	// rules that replace the embedded rule table.  It implies Formats.
+	FormatRules string
+	// Lenient enables repair of malformed fields and subfields instead of
+	// skipping the records that contain them.  Repairs are written to a
+	// diagnostics table.
+	Lenient bool
+	// FieldCksum enables checksums of individual fields, so that an
+	// incremental update rewrites only the fields of a changed record that
+	// have changed.
+	FieldCksum bool
+	// CksumIgnore lists tag patterns, e.g. "9XX", of fields that are
+	// excluded from change detection, so that an incremental update does
+	// not rewrite a record in which only those fields have changed.
+	CksumIgnore      []string
+	derived          []*derived.Table
+	mode             *util.Mode
+	filter           *srs.Filter
+	content          *util.Content
+	mapping          *mapping.Mapping
+	formats          *format.Rules
+	hosts            *link.Hosts
+	cksumIgnore      *inc.CksumIgnore
+	validateSeverity map[validate.Severity]bool
 }
 
 type PrintErr func(string, ...interface{})
@@ -44,8 +149,6 @@ type Locations struct {
 }
 
 var tableoutSchema = "marctab"
-var tableoutTable = "_mt"
-var tableout = tableoutSchema + "." + tableoutTable
 
 var allFields = util.GetAllFieldNames()
 
@@ -84,23 +187,10 @@ func main() {
 */
 
 func Run(opts *TransformOptions) error {
-	opts.Loc = setupLocations(opts)
-	// Read database configuration
-	var host, port, user, password, dbname, sslmode string
-	var err error
-	if opts.Metadb {
-		host, port, user, password, dbname, sslmode, err = readConfigMetadb(opts)
-		if err != nil {
-			return err
-		}
-	} else {
-		host, port, user, password, dbname, sslmode, err = readConfigLDP1(opts)
-		if err != nil {
-			return err
-		}
+	connString, err := readConnString(opts)
+	if err != nil {
+		return err
 	}
-	connString := "host=" + host + " port=" + port + " user=" + user + " password=" + password + " dbname=" +
-		dbname + " sslmode=" + sslmode
 	conn, err := util.ConnectDB(context.TODO(), connString)
 	if err != nil {
 		return err
@@ -109,10 +199,51 @@ func Run(opts *TransformOptions) error {
 	if err = setupSchema(conn); err != nil {
 		return fmt.Errorf("setting up schema: %v", err)
 	}
+	modes := []*util.Mode{util.BibMode}
+	if opts.Holdings {
+		modes = append(modes, util.HoldingsMode)
+	}
+	if opts.Authority {
+		modes = append(modes, util.AuthorityMode)
+	}
+	for _, mode := range modes {
+		if err = runMode(opts, mode, conn, connString); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runMode performs a full or incremental update of the output for one record
+// type.
+func runMode(opts *TransformOptions, mode *util.Mode, conn *pgx.Conn, connString string) error {
+	opts.mode = mode
+	opts.filter = setupFilter(opts, mode)
+	opts.content = &util.Content{NFC: opts.NFC, Folded: opts.ContentFolded}
+	opts.Loc = setupLocations(opts, mode)
+	cksumIgnore, err := inc.ParseCksumIgnore(opts.CksumIgnore)
+	if err != nil {
+		return fmt.Errorf("parsing checksum ignore list: %v", err)
+	}
+	opts.cksumIgnore = cksumIgnore
+	tables, closeReports, err := derivedTables(opts)
+	if err != nil {
+		return err
+	}
+	defer closeReports()
+	opts.derived = tables
+	if opts.Verbose >= 1 && mode != util.BibMode {
+		opts.PrintErr("transforming %s records", mode.Name)
+	}
 	var incUpdateAvail bool
-	if incUpdateAvail, err = inc.IncUpdateAvail(conn); err != nil {
+	if incUpdateAvail, err = inc.IncUpdateAvail(conn, mode, opts.config()); err != nil {
 		return err
 	}
+	if incUpdateAvail {
+		if incUpdateAvail, err = derivedAvail(conn, opts.derived); err != nil {
+			return err
+		}
+	}
 	var retry bool
 	for {
 		if !retry && incUpdateAvail && !opts.FullUpdate && opts.CSVFileName == "" {
@@ -120,7 +251,8 @@ func Run(opts *TransformOptions) error {
 				opts.PrintErr("starting incremental update")
 			}
 			err = inc.IncrementalUpdate(connString, opts.Loc.SrsRecords, opts.Loc.SrsMarc, opts.Loc.SrsMarcAttr,
-				opts.Loc.tablefinal(), opts.PrintErr, opts.Verbose)
+				opts.Loc.tablefinal(), mode, opts.newParser(), opts.filter, opts.content, opts.FieldCksum,
+				opts.cksumIgnore, opts.derived, opts.PrintErr, opts.Verbose)
 			if err != nil {
 				opts.PrintErr("restarting with full update due to early termination: %v", err)
 				retry = true
@@ -131,7 +263,10 @@ func Run(opts *TransformOptions) error {
 				opts.PrintErr("starting full update")
 			}
 			if err = fullUpdate(opts, connString, opts.PrintErr); err != nil {
-				_, _ = conn.Exec(context.TODO(), "DROP TABLE IF EXISTS "+tableout)
+				_, _ = conn.Exec(context.TODO(), "DROP TABLE IF EXISTS "+opts.tableout())
+				for _, t := range dbTables(opts.derived) {
+					_, _ = conn.Exec(context.TODO(), "DROP TABLE IF EXISTS "+t.Temp())
+				}
 				return err
 			}
 		}
@@ -142,20 +277,53 @@ func Run(opts *TransformOptions) error {
 	return nil
 }
 
-func setupLocations(opts *TransformOptions) Locations {
+// readConnString reads the database configuration and returns a connection
+// string.
+func readConnString(opts *TransformOptions) (string, error) {
+	var host, port, user, password, dbname, sslmode string
+	var err error
+	if opts.Metadb {
+		host, port, user, password, dbname, sslmode, err = readConfigMetadb(opts)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		host, port, user, password, dbname, sslmode, err = readConfigLDP1(opts)
+		if err != nil {
+			return "", err
+		}
+	}
+	return "host=" + host + " port=" + port + " user=" + user + " password=" + password + " dbname=" +
+		dbname + " sslmode=" + sslmode, nil
+}
+
+// setupFilter returns the record selection filter for a mode.
+func setupFilter(opts *TransformOptions, mode *util.Mode) *srs.Filter {
+	f := srs.NewFilter(mode.RecordType)
+	if len(opts.States) != 0 {
+		f.States = opts.States
+	}
+	if mode == util.BibMode && len(opts.RecordTypes) != 0 {
+		f.RecordTypes = opts.RecordTypes
+	}
+	f.RequireID = !opts.IncludeNoID
+	return f
+}
+
+func setupLocations(opts *TransformOptions, mode *util.Mode) Locations {
 	loc := Locations{
 		SrsRecords:       "folio_source_record.records_lb",
 		SrsMarc:          "folio_source_record.marc_records_lb",
 		SrsMarcAttr:      "content",
 		TablefinalSchema: "folio_source_record",
-		TablefinalTable:  "marc__t",
+		TablefinalTable:  "marc" + mode.Suffix + "__t",
 	}
 	if !opts.Metadb { // LDP1
 		loc.SrsRecords = "public.srs_records"
 		loc.SrsMarc = "public.srs_marc"
 		loc.SrsMarcAttr = "data"
 		loc.TablefinalSchema = "public"
-		loc.TablefinalTable = "srs_marctab"
+		loc.TablefinalTable = "srs_marctab" + mode.Suffix
 	}
 	if opts.SRSRecords != "" {
 		loc.SrsRecords = opts.SRSRecords
@@ -195,16 +363,16 @@ func fullUpdate(opts *TransformOptions, connString string, printerr PrintErr) er
 	}
 	// Vacuum in case previous run was not completed.
 	_ = util.Vacuum(context.TODO(), dbc, opts.Loc.tablefinal())
-	_ = inc.VacuumCksum(context.TODO(), dbc)
+	_ = inc.VacuumCksum(context.TODO(), dbc, opts.mode, opts.FieldCksum)
 	if opts.CSVFileName != "" {
-		if csvFile, err = os.Create(opts.CSVFileName); err != nil {
+		if csvFile, err = os.Create(opts.csvFileName()); err != nil {
 			return err
 		}
 		defer func(csvFile *os.File) {
 			_ = csvFile.Close()
 		}(csvFile)
 		if opts.Verbose >= 1 {
-			printerr("output will be written to file: %s", opts.CSVFileName)
+			printerr("output will be written to file: %s", opts.csvFileName())
 		}
 	}
 	// Process MARC data
@@ -234,7 +402,7 @@ func fullUpdate(opts *TransformOptions, connString string, printerr PrintErr) er
 		if inputCount > 0 {
 			startCksum := time.Now()
 			if err = inc.CreateCksum(dbc, opts.Loc.SrsRecords, opts.Loc.SrsMarc, opts.Loc.tablefinal(),
-				opts.Loc.SrsMarcAttr); err != nil {
+				opts.Loc.SrsMarcAttr, opts.mode, opts.filter, opts.FieldCksum, opts.cksumIgnore, opts.config()); err != nil {
 				return err
 			}
 			if opts.Verbose >= 1 {
@@ -244,7 +412,7 @@ func fullUpdate(opts *TransformOptions, connString string, printerr PrintErr) er
 			if err = util.Vacuum(context.TODO(), dbc, opts.Loc.tablefinal()); err != nil {
 				return err
 			}
-			if err = inc.VacuumCksum(context.TODO(), dbc); err != nil {
+			if err = inc.VacuumCksum(context.TODO(), dbc, opts.mode, opts.FieldCksum); err != nil {
 				return err
 			}
 			if opts.Verbose >= 1 {
@@ -265,7 +433,7 @@ func fullUpdate(opts *TransformOptions, connString string, printerr PrintErr) er
 func process(opts *TransformOptions, dbc *util.DBC, printerr PrintErr) (int64, int64, error) {
 	var err error
 	var store *local.Store
-	if store, err = local.NewStore(opts.Datadir); err != nil {
+	if store, err = local.NewStore(opts.Datadir, opts.content.Folded, opts.filter.NonActual()); err != nil {
 		return 0, 0, err
 	}
 	defer store.Close()
@@ -292,6 +460,7 @@ func process(opts *TransformOptions, dbc *util.DBC, printerr PrintErr) (int64, i
 func setupTables(opts *TransformOptions, dbc *util.DBC) error {
 	var err error
 	var q string
+	tableout := opts.tableout()
 	_, _ = dbc.Conn.Exec(context.TODO(), "DROP TABLE IF EXISTS "+tableout)
 	if opts.TrigramIndex && !util.IsTrgmAvailable(dbc) {
 		return fmt.Errorf("unable to access pg_trgm module extension")
@@ -305,19 +474,28 @@ func setupTables(opts *TransformOptions, dbc *util.DBC) error {
 		"    srs_id uuid NOT NULL," +
 		"    line smallint NOT NULL," +
 		"    matched_id uuid NOT NULL," +
-		"    instance_hrid varchar(32) NOT NULL," +
-		"    instance_id uuid NOT NULL," +
+		"    " + opts.mode.HRIDColumn + " varchar(32) NOT NULL," +
+		"    " + opts.mode.IDColumn + " uuid NOT NULL," +
 		"    field varchar(3) NOT NULL," +
 		"    ind1 varchar(1) NOT NULL," +
 		"    ind2 varchar(1) NOT NULL," +
 		"    ord smallint NOT NULL," +
 		"    sf varchar(1) NOT NULL," +
-		"    content varchar(65535)" + lz4 + " NOT NULL" +
-		") PARTITION BY LIST (field);"
+		"    content varchar(65535)" + lz4 + " NOT NULL"
+	if opts.content.Folded {
+		q += ", content_folded varchar(65535)" + lz4 + " NOT NULL"
+	}
+	if opts.filter.NonActual() {
+		q += ", state varchar(16) NOT NULL"
+	}
+	q += ") PARTITION BY LIST (field);"
 	if _, err = dbc.Conn.Exec(context.TODO(), q); err != nil {
 		return fmt.Errorf("creating table: %s", err)
 	}
-	q = "COMMENT ON TABLE " + tableout + " IS 'current SRS MARC records in tabular form'"
+	q = "COMMENT ON TABLE " + tableout + " IS 'current SRS MARC " + opts.mode.Name + " records in tabular form'"
+	if opts.filter.NonActual() {
+		q = "COMMENT ON TABLE " + tableout + " IS 'SRS MARC " + opts.mode.Name + " records in tabular form'"
+	}
 	if _, err = dbc.Conn.Exec(context.TODO(), q); err != nil {
 		return fmt.Errorf("adding comment on table: %s", err)
 	}
@@ -330,6 +508,11 @@ func setupTables(opts *TransformOptions, dbc *util.DBC) error {
 			return fmt.Errorf("creating partition: %s", err)
 		}
 	}
+	for _, t := range dbTables(opts.writtenTables()) {
+		if err = derived.Create(context.TODO(), dbc.Conn, t); err != nil {
+			return err
+		}
+	}
 	_, _ = dbc.Conn.Exec(context.TODO(), "DROP TABLE IF EXISTS ldpmarc.cksum")
 	_, _ = dbc.Conn.Exec(context.TODO(), "DROP TABLE IF EXISTS ldpmarc.metadata")
 	_, _ = dbc.Conn.Exec(context.TODO(), "DROP SCHEMA IF EXISTS ldpmarc")
@@ -352,26 +535,48 @@ func processAll(opts *TransformOptions, dbc *util.DBC, store *local.Store, print
 	var err error
 	var msg *string
 	var writeCount int64
-	var q = "SELECT r.id, r.matched_id, r.external_hrid instance_hrid, r.state, m." + opts.Loc.SrsMarcAttr +
-		"::text FROM " + opts.Loc.SrsRecords + " r JOIN " + opts.Loc.SrsMarc + " m ON r.id = m.id"
+	var q = "SELECT r.id, r.matched_id, r.external_hrid instance_hrid, r.state, r.record_type::text, m." +
+		opts.Loc.SrsMarcAttr + "::text FROM " + opts.Loc.SrsRecords + " r JOIN " + opts.Loc.SrsMarc +
+		" m ON r.id = m.id WHERE " + opts.filter.RecordSQL()
+	// Derived tables are written using a separate connection, which is
+	// not needed if only reports are written.
+	tables := opts.writtenTables()
+	var db derived.DB
+	if len(dbTables(tables)) != 0 {
+		var connW *pgx.Conn
+		if connW, err = util.ConnectDB(context.TODO(), dbc.ConnString); err != nil {
+			return 0, fmt.Errorf("opening connection for writing: %v", err)
+		}
+		defer connW.Close(context.TODO())
+		db = connW
+	}
+	dw := derived.NewWriter(db, tables, true)
 	var rows pgx.Rows
 	if rows, err = dbc.Conn.Query(context.TODO(), q); err != nil {
 		return 0, fmt.Errorf("selecting marc records: %v", err)
 	}
+	parser := opts.newParser()
 	for rows.Next() {
-		var id, matchedID, instanceHRID, state, data *string
-		if err = rows.Scan(&id, &matchedID, &instanceHRID, &state, &data); err != nil {
+		var id, matchedID, instanceHRID, state, recordType, data *string
+		if err = rows.Scan(&id, &matchedID, &instanceHRID, &state, &recordType, &data); err != nil {
 			return 0, fmt.Errorf("scanning records: %v", err)
 		}
 		var record local.Record
 		var instanceID string
 		var mrecs []srs.Marc
+		var anomalies []srs.Anomaly
 		var skip bool
-		id, matchedID, instanceHRID, instanceID, mrecs, skip = util.Transform(id, matchedID, instanceHRID,
-			state, data, printerr, opts.Verbose)
+		id, matchedID, instanceHRID, instanceID, mrecs, anomalies, skip = util.Transform(id, matchedID, instanceHRID,
+			state, recordType, data, parser, opts.filter, opts.content, printerr, opts.Verbose)
 		if skip {
 			continue
 		}
+		if len(mrecs) != 0 {
+			err = dw.Write(context.TODO(), &derived.Record{SRSID: *id, InstanceID: instanceID, Leader: parser.Leader(), Marc: mrecs, Anomalies: anomalies})
+			if err != nil {
+				return 0, err
+			}
+		}
 		var m srs.Marc
 		for _, m = range mrecs {
 			if opts.CSVFileName == "" {
@@ -386,6 +591,10 @@ func processAll(opts *TransformOptions, dbc *util.DBC, store *local.Store, print
 				record.Ord = m.Ord
 				record.SF = m.SF
 				record.Content = m.Content
+				if opts.content.Folded {
+					record.ContentFolded = textnorm.Fold(m.Content)
+				}
+				record.State = *state
 				msg, err = store.Write(&record)
 				if err != nil {
 					return 0, fmt.Errorf("writing record: %v: %v", err, record)
@@ -396,7 +605,14 @@ func processAll(opts *TransformOptions, dbc *util.DBC, store *local.Store, print
 				}
 				writeCount++
 			} else {
-				_, _ = fmt.Fprintf(csvFile, "%q,%d,%q,%q,%q,%q,%q,%q,%d,%q,%q\n", *id, m.Line, *matchedID, *instanceHRID, instanceID, m.Field, m.Ind1, m.Ind2, m.Ord, m.SF, m.Content)
+				line := fmt.Sprintf("%q,%d,%q,%q,%q,%q,%q,%q,%d,%q,%q", *id, m.Line, *matchedID, *instanceHRID, instanceID, m.Field, m.Ind1, m.Ind2, m.Ord, m.SF, m.Content)
+				if opts.content.Folded {
+					line += fmt.Sprintf(",%q", textnorm.Fold(m.Content))
+				}
+				if opts.filter.NonActual() {
+					line += fmt.Sprintf(",%q", *state)
+				}
+				_, _ = fmt.Fprintln(csvFile, line)
 				writeCount++
 			}
 		}
@@ -406,6 +622,10 @@ func processAll(opts *TransformOptions, dbc *util.DBC, store *local.Store, print
 	}
 	rows.Close()
 
+	if err = dw.Flush(context.TODO()); err != nil {
+		return 0, err
+	}
+
 	if err = store.FinishWriting(); err != nil {
 		return 0, err
 	}
@@ -416,6 +636,13 @@ func processAll(opts *TransformOptions, dbc *util.DBC, store *local.Store, print
 
 	startTime = time.Now()
 
+	cols := []string{"srs_id", "line", "matched_id", opts.mode.HRIDColumn, opts.mode.IDColumn, "field", "ind1", "ind2", "ord", "sf", "content"}
+	if opts.content.Folded {
+		cols = append(cols, "content_folded")
+	}
+	if opts.filter.NonActual() {
+		cols = append(cols, "state")
+	}
 	var f string
 	for _, f = range allFields {
 		src, err := store.ReadSource(f, printerr)
@@ -423,8 +650,8 @@ func processAll(opts *TransformOptions, dbc *util.DBC, store *local.Store, print
 			return 0, err
 		}
 		_, err = dbc.Conn.CopyFrom(context.TODO(),
-			pgx.Identifier{tableoutSchema, tableoutTable + f},
-			[]string{"srs_id", "line", "matched_id", "instance_hrid", "instance_id", "field", "ind1", "ind2", "ord", "sf", "content"},
+			pgx.Identifier{tableoutSchema, "_" + opts.mode.PartitionPrefix + f},
+			cols,
 			src)
 		if err != nil {
 			return 0, fmt.Errorf("copying to database: %v", err)
@@ -446,15 +673,29 @@ func index(opts *TransformOptions, dbc *util.DBC, printerr PrintErr) error {
 	var cols = []string{
 		"srs_id",
 		"matched_id",
-		"instance_hrid",
-		"instance_id",
+		opts.mode.HRIDColumn,
+		opts.mode.IDColumn,
 		"sf"}
+	if opts.filter.NonActual() {
+		cols = append(cols, "state")
+	}
 	if opts.TrigramIndex {
 		cols = append(cols, "content")
+		if opts.content.Folded {
+			cols = append(cols, "content_folded")
+		}
 	}
 	if err = indexColumns(opts, dbc, cols, printerr); err != nil {
 		return err
 	}
+	for _, t := range dbTables(opts.derived) {
+		if opts.Verbose >= 2 {
+			printerr("creating indexes: %s", t.Name)
+		}
+		if err = derived.Index(context.TODO(), dbc.Conn, t); err != nil {
+			return err
+		}
+	}
 	if opts.Verbose >= 1 {
 		printerr(" %s index", util.ElapsedTime(startIndex))
 	}
@@ -462,11 +703,12 @@ func index(opts *TransformOptions, dbc *util.DBC, printerr PrintErr) error {
 }
 
 func indexColumns(opts *TransformOptions, dbc *util.DBC, cols []string, printerr PrintErr) error {
+	tableout := opts.tableout()
 	for _, c := range cols {
 		if opts.Verbose >= 2 {
 			printerr("creating index: %s", c)
 		}
-		if c == "content" {
+		if c == "content" || c == "content_folded" {
 			var q = "CREATE INDEX ON " + tableout + " USING GIN (" + c + " gin_trgm_ops)"
 			if _, err := dbc.Conn.Exec(context.TODO(), q); err != nil {
 				return fmt.Errorf("creating index with pg_trgm extension: %s: %s", c, err)
@@ -482,16 +724,21 @@ func indexColumns(opts *TransformOptions, dbc *util.DBC, cols []string, printerr
 }
 
 func replace(opts *TransformOptions, dbc *util.DBC) error {
-	// Clean up old tables
-	q := "DROP TABLE IF EXISTS folio_source_record.marctab"
-	_, err := dbc.Conn.Exec(context.TODO(), q)
-	if err != nil {
-		return fmt.Errorf("dropping table: %s", err)
-	}
-	q = "DROP TABLE IF EXISTS public.srs_marctab"
-	_, err = dbc.Conn.Exec(context.TODO(), q)
-	if err != nil {
-		return fmt.Errorf("dropping table: %s", err)
+	var q string
+	var err error
+	tableout := opts.tableout()
+	if opts.mode == util.BibMode {
+		// Clean up old tables
+		q = "DROP TABLE IF EXISTS folio_source_record.marctab"
+		_, err = dbc.Conn.Exec(context.TODO(), q)
+		if err != nil {
+			return fmt.Errorf("dropping table: %s", err)
+		}
+		q = "DROP TABLE IF EXISTS public.srs_marctab"
+		_, err = dbc.Conn.Exec(context.TODO(), q)
+		if err != nil {
+			return fmt.Errorf("dropping table: %s", err)
+		}
 	}
 
 	q = "DROP TABLE IF EXISTS " + tableoutSchema + "." + opts.Loc.TablefinalTable
@@ -520,12 +767,20 @@ func replace(opts *TransformOptions, dbc *util.DBC) error {
 		if err != nil {
 			return fmt.Errorf("dropping table: %s", err)
 		}
-		q = "ALTER TABLE " + tableout + field + " RENAME TO mt" + field
+		q = "ALTER TABLE " + tableout + field + " RENAME TO " + opts.mode.PartitionPrefix + field
 		_, err = dbc.Conn.Exec(context.TODO(), q)
 		if err != nil {
 			return fmt.Errorf("renaming table: %s", err)
 		}
 	}
+	for _, t := range dbTables(opts.derived) {
+		if err = derived.Replace(context.TODO(), dbc.Conn, t); err != nil {
+			return err
+		}
+	}
+	if err = dropStale(opts, dbc.Conn); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -540,6 +795,16 @@ func grant(opts *TransformOptions, dbc *util.DBC, user string) error {
 	if _, err = dbc.Conn.Exec(context.TODO(), q); err != nil {
 		return fmt.Errorf("table permission: %s", err)
 	}
+	for _, t := range dbTables(opts.derived) {
+		q = "GRANT USAGE ON SCHEMA " + t.Schema + " TO " + user
+		if _, err = dbc.Conn.Exec(context.TODO(), q); err != nil {
+			return fmt.Errorf("schema permission: %s", err)
+		}
+		q = "GRANT SELECT ON " + t.Final() + " TO " + user
+		if _, err = dbc.Conn.Exec(context.TODO(), q); err != nil {
+			return fmt.Errorf("table permission: %s", err)
+		}
+	}
 	return nil
 }
 
@@ -584,6 +849,66 @@ func (l Locations) tablefinal() string {
 	return l.TablefinalSchema + "." + l.TablefinalTable
 }
 
+// config returns a description of the record selection and output options
+// that affect the output tables, which is used to determine whether
+// incremental update is available.
+func (o *TransformOptions) config() string {
+	c := o.filter.String() + ";" + o.content.String()
+	if o.mapping != nil {
+		c += ";mapping=" + o.mapping.Signature()
+	}
+	if o.formats != nil && o.formats != format.Default {
+		c += ";formats=" + o.formats.Signature()
+	}
+	if o.hosts != nil {
+		c += ";hosts=" + o.hosts.Signature()
+	}
+	if o.validateSeverity != nil {
+		c += ";validate=" + validate.SeveritySignature(o.validateSeverity)
+	}
+	if o.Lenient {
+		c += ";lenient"
+	}
+	if o.FieldCksum {
+		c += ";field-cksum"
+	}
+	if o.cksumIgnore != nil {
+		c += ";cksum-ignore=" + o.cksumIgnore.String()
+	}
+	// The list of derived tables is last, so that it can be read back by
+	// configTables.
+	var names []string
+	for _, t := range dbTables(o.derived) {
+		names = append(names, t.Final())
+	}
+	c += ";tables=" + strings.Join(names, ",")
+	return c
+}
+
+// newParser returns a Parser configured by the options.
+func (o *TransformOptions) newParser() *srs.Parser {
+	p := srs.NewParser()
+	p.Lenient = o.Lenient
+	return p
+}
+
+// csvFileName returns the name of the CSV output file for the current record
+// type.  The mode suffix is added to the file name for record types other
+// than bibliographic, e.g. "out_holdings.csv", so that each record type is
+// written to a separate file.
+func (o *TransformOptions) csvFileName() string {
+	if o.mode.Suffix == "" {
+		return o.CSVFileName
+	}
+	ext := filepath.Ext(o.CSVFileName)
+	return strings.TrimSuffix(o.CSVFileName, ext) + o.mode.Suffix + ext
+}
+
+// tableout returns the name of the output table used during a full update.
+func (o *TransformOptions) tableout() string {
+	return tableoutSchema + "._" + o.mode.PartitionPrefix
+}
+
 func readConfigMetadb(opts *TransformOptions) (string, string, string, string, string, string, error) {
 	var mdbconf = filepath.Join(opts.Datadir, "metadb.conf")
 	cfg, err := ini.Load(mdbconf)
@@ -0,0 +1,173 @@
+package marc
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/library-data-platform/ldpmarc/marc/profile"
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/util"
+)
+
+// ProfileOptions configures field and subfield frequency profiling.
+type ProfileOptions struct {
+	Datadir     string
+	Metadb      bool
+	SRSRecords  string
+	SRSMarc     string
+	SRSMarcAttr string
+	// FileName is the name of a file containing SRS MARC records in JSON
+	// format, one per line, to be read instead of the database.
+	FileName string
+	// CSVFileName is the name of a file to which CSV output is written.
+	// If empty, a summary table is written to standard output.
+	CSVFileName string
+	// Tags lists tag patterns to profile, e.g. "9XX".
+	Tags     []string
+	Samples  int
+	Verbose  int
+	PrintErr PrintErr
+}
+
+// Profile counts records and occurrences of tags, indicators, and subfields
+// in SRS MARC records.
+func Profile(opts *ProfileOptions) error {
+	p, err := profile.New(opts.Tags, opts.Samples)
+	if err != nil {
+		return err
+	}
+	if opts.FileName != "" {
+		err = profileFile(opts, p)
+	} else {
+		err = profileDB(opts, p)
+	}
+	if err != nil {
+		return err
+	}
+	if opts.CSVFileName == "" {
+		return p.WriteSummary(os.Stdout)
+	}
+	f, err := os.Create(opts.CSVFileName)
+	if err != nil {
+		return err
+	}
+	if err = p.WriteCSV(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing CSV file: %s: %v", opts.CSVFileName, err)
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if opts.Verbose >= 1 {
+		opts.PrintErr("profile written to file: %s", opts.CSVFileName)
+	}
+	return nil
+}
+
+func profileFile(opts *ProfileOptions, p *profile.Profile) error {
+	// Records read from a file need not have an instance identifier.
+	filter := srs.NewFilter(srs.Bib)
+	filter.RequireID = false
+	f, err := os.Open(opts.FileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	parser := srs.NewParser()
+	var n int64
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("reading file: %s: %v", opts.FileName, err)
+		}
+		n++
+		if data := strings.TrimSpace(line); data != "" {
+			// Records read from a file have no state and are
+			// treated as current.
+			mrecs, _, anomalies, terr := parser.Transform(&data, "ACTUAL", "", filter)
+			switch {
+			case terr != nil:
+				opts.PrintErr("skipping record: line %d: %s", n, terr)
+			case len(mrecs) != 0:
+				if opts.Verbose >= 2 {
+					for _, a := range anomalies {
+						opts.PrintErr("anomaly in record: line %d: %s", n, a)
+					}
+				}
+				p.Add(mrecs)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return nil
+}
+
+func profileDB(opts *ProfileOptions, p *profile.Profile) error {
+	topts := &TransformOptions{
+		Datadir:     opts.Datadir,
+		Metadb:      opts.Metadb,
+		SRSRecords:  opts.SRSRecords,
+		SRSMarc:     opts.SRSMarc,
+		SRSMarcAttr: opts.SRSMarcAttr,
+	}
+	loc := setupLocations(topts, util.BibMode)
+	filter := srs.NewFilter(srs.Bib)
+	connString, err := readConnString(topts)
+	if err != nil {
+		return err
+	}
+	conn, err := util.ConnectDB(context.TODO(), connString)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.TODO())
+	var q = "SELECT r.id, r.state, r.record_type::text, m." + loc.SrsMarcAttr + "::text FROM " + loc.SrsRecords +
+		" r JOIN " + loc.SrsMarc + " m ON r.id = m.id WHERE " + filter.RecordSQL()
+	var rows pgx.Rows
+	if rows, err = conn.Query(context.TODO(), q); err != nil {
+		return fmt.Errorf("selecting marc records: %v", err)
+	}
+	defer rows.Close()
+	parser := srs.NewParser()
+	for rows.Next() {
+		var id, state, recordType, data *string
+		if err = rows.Scan(&id, &state, &recordType, &data); err != nil {
+			return fmt.Errorf("scanning records: %v", err)
+		}
+		if id == nil || data == nil || state == nil {
+			continue
+		}
+		rt := ""
+		if recordType != nil {
+			rt = *recordType
+		}
+		mrecs, _, anomalies, err := parser.Transform(data, *state, rt, filter)
+		if err != nil {
+			opts.PrintErr("skipping record: %s: %s", *id, err)
+			continue
+		}
+		if opts.Verbose >= 2 {
+			for _, a := range anomalies {
+				opts.PrintErr("anomaly in record: %s: %s", *id, a)
+			}
+		}
+		if len(mrecs) != 0 {
+			p.Add(mrecs)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("row error: %v", err)
+	}
+	if opts.Verbose >= 1 {
+		opts.PrintErr("%d records profiled", p.Records())
+	}
+	return nil
+}
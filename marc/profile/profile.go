@@ -0,0 +1,213 @@
+// Package profile counts the use of fields, indicators, and subfields in
+// transformed SRS MARC records.
+package profile
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/library-data-platform/ldpmarc/marc/inc"
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+// Count is the number of records and occurrences in which a tag, tag and
+// indicator combination, or tag and subfield combination is found, along with
+// sample values.
+type Count struct {
+	Records     int64
+	Occurrences int64
+	Samples     []string
+	lastRecord  int64
+}
+
+type key struct {
+	tag  string
+	ind1 string
+	ind2 string
+	sf   string
+	kind int
+}
+
+const (
+	kindTag = iota
+	kindInd
+	kindSF
+)
+
+var kindNames = []string{"tag", "indicators", "subfield"}
+
+// Profile accumulates counts over a set of records.
+type Profile struct {
+	tags    *inc.CksumIgnore
+	samples int
+	records int64
+	counts  map[key]*Count
+}
+
+// New returns a Profile that counts tags matching any of patterns, or all
+// tags if patterns is empty, and retains up to samples distinct sample values
+// for each tag and subfield.  Patterns are interpreted as for the -cksum-ignore
+// option, in which "X" matches any digit, e.g. "9XX".
+func New(patterns []string, samples int) (*Profile, error) {
+	tags, err := inc.ParseCksumIgnore(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &Profile{
+		tags:    tags,
+		samples: samples,
+		counts:  make(map[key]*Count),
+	}, nil
+}
+
+// Records returns the number of records added.
+func (p *Profile) Records() int64 {
+	return p.records
+}
+
+// Add counts the rows of a transformed record.
+func (p *Profile) Add(mrecs []srs.Marc) {
+	p.records++
+	for i, m := range mrecs {
+		if !p.match(m.Field) {
+			continue
+		}
+		if i == 0 || mrecs[i-1].Field != m.Field || mrecs[i-1].Ord != m.Ord {
+			c := p.count(key{tag: m.Field, kind: kindTag})
+			if m.SF == "" {
+				p.sample(c, m.Content)
+			}
+			if m.Field >= "010" {
+				p.count(key{tag: m.Field, ind1: m.Ind1, ind2: m.Ind2, kind: kindInd})
+			}
+		}
+		if m.SF != "" {
+			p.sample(p.count(key{tag: m.Field, sf: m.SF, kind: kindSF}), m.Content)
+		}
+	}
+}
+
+func (p *Profile) count(k key) *Count {
+	c, ok := p.counts[k]
+	if !ok {
+		// The key is copied because its strings may share memory with
+		// the record they were read from.
+		k.tag, k.ind1, k.ind2, k.sf = strings.Clone(k.tag), strings.Clone(k.ind1), strings.Clone(k.ind2),
+			strings.Clone(k.sf)
+		c = new(Count)
+		p.counts[k] = c
+	}
+	c.Occurrences++
+	if c.lastRecord != p.records {
+		c.Records++
+		c.lastRecord = p.records
+	}
+	return c
+}
+
+func (p *Profile) sample(c *Count, value string) {
+	if len(c.Samples) >= p.samples {
+		return
+	}
+	for _, s := range c.Samples {
+		if s == value {
+			return
+		}
+	}
+	c.Samples = append(c.Samples, strings.Clone(value))
+}
+
+func (p *Profile) match(tag string) bool {
+	return p.tags == nil || p.tags.Match(tag)
+}
+
+func (p *Profile) sortedKeys() []key {
+	keys := make([]key, 0, len(p.counts))
+	for k := range p.counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch {
+		case a.tag != b.tag:
+			return a.tag < b.tag
+		case a.kind != b.kind:
+			return a.kind < b.kind
+		case a.ind1 != b.ind1:
+			return a.ind1 < b.ind1
+		case a.ind2 != b.ind2:
+			return a.ind2 < b.ind2
+		default:
+			return a.sf < b.sf
+		}
+	})
+	return keys
+}
+
+// WriteCSV writes the counts to w in CSV format.
+func (p *Profile) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"kind", "tag", "ind1", "ind2", "sf", "records", "occurrences", "samples"}); err != nil {
+		return err
+	}
+	for _, k := range p.sortedKeys() {
+		c := p.counts[k]
+		rec := []string{
+			kindNames[k.kind],
+			k.tag,
+			k.ind1,
+			k.ind2,
+			k.sf,
+			strconv.FormatInt(c.Records, 10),
+			strconv.FormatInt(c.Occurrences, 10),
+			strings.Join(c.Samples, " | "),
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteSummary writes the counts to w as a table.
+func (p *Profile) WriteSummary(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "%d records\n\n", p.records)
+	_, _ = fmt.Fprintln(tw, "TAG\tIND\tSF\tRECORDS\tOCCURRENCES\tSAMPLES")
+	for _, k := range p.sortedKeys() {
+		c := p.counts[k]
+		var ind string
+		if k.kind == kindInd {
+			ind = blank(k.ind1) + blank(k.ind2)
+		}
+		var sf string
+		if k.kind == kindSF {
+			sf = "$" + k.sf
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\n", k.tag, ind, sf, c.Records, c.Occurrences,
+			truncate(strings.Join(c.Samples, " | "), 60))
+	}
+	return tw.Flush()
+}
+
+func blank(ind string) string {
+	if ind == " " || ind == "" {
+		return "#"
+	}
+	return ind
+}
+
+func truncate(s string, n int) string {
+	s = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
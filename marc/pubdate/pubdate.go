@@ -0,0 +1,216 @@
+// Package pubdate extracts and normalizes publication dates from the 008,
+// 260, and 264 fields of transformed SRS MARC records.
+package pubdate
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/fixed"
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+// Confidence levels.
+const (
+	// High indicates an exact year in 008 that agrees with 260 or 264, if
+	// present.
+	High = "high"
+	// Medium indicates an exact year in 008 that disagrees with 260 or
+	// 264, or an exact year found only in 260 or 264.
+	Medium = "medium"
+	// Low indicates a range of years implied by an incomplete or
+	// questionable date, such as "19uu" or "[199-?]".
+	Low = "low"
+)
+
+// Date is a normalized publication date.  YearStart and YearEnd are 0 if
+// they could not be determined; YearEnd is also 0 if the range is open, as
+// for a continuing resource that is currently published.
+type Date struct {
+	// DateType is 008/06, or "" if there is no 008.
+	DateType  string
+	YearStart int
+	YearEnd   int
+	// Confidence is High, Medium, Low, or "" if no date was found.
+	Confidence string
+	// Source is the field from which the years were taken:  "008",
+	// "260", "264", or "" if no date was found.
+	Source string
+}
+
+// rangeTypes lists the 008/06 date types for which 008/11-14 is the end of
+// a range of dates.
+var rangeTypes = map[string]bool{
+	"c": true, "d": true, "i": true, "k": true, "m": true, "q": true, "u": true,
+}
+
+// Extract returns the normalized publication date of a transformed record.
+func Extract(mrecs []srs.Marc) Date {
+	var d Date
+	var f008, c260, c264, c264pub string
+	for _, m := range mrecs {
+		switch {
+		case m.Field == "008" && f008 == "":
+			f008 = m.Content
+		case m.Field == "260" && m.SF == "c" && c260 == "":
+			c260 = m.Content
+		case m.Field == "264" && m.SF == "c" && m.Ind2 == "1" && c264pub == "":
+			c264pub = m.Content
+		case m.Field == "264" && m.SF == "c" && c264 == "":
+			c264 = m.Content
+		}
+	}
+	// A 264 with second indicator 1 (publication) is preferred over
+	// others, such as copyright dates.
+	c, source := c260, "260"
+	if c == "" {
+		c, source = first(c264pub, c264), "264"
+	}
+	var cs, ce int
+	var cexact, cok bool
+	if c != "" {
+		cs, ce, cexact, cok = ParseStatement(c)
+	}
+	date1, ok1 := fixed.Slice(f008, 7, 11)
+	date2, ok2 := fixed.Slice(f008, 11, 15)
+	if ok1 && ok2 {
+		dateType, _ := fixed.Slice(f008, 6, 7)
+		d.DateType = strings.TrimSpace(dateType)
+		if s, e, exact, ok := parse008(d.DateType, date1, date2); ok {
+			d.YearStart, d.YearEnd, d.Source = s, e, "008"
+			switch {
+			case !exact:
+				d.Confidence = Low
+			case cok && (cs < s || (e != 0 && cs > e)):
+				d.Confidence = Medium
+			default:
+				d.Confidence = High
+			}
+			return d
+		}
+	}
+	if cok {
+		d.YearStart, d.YearEnd, d.Source = cs, ce, source
+		if cexact {
+			d.Confidence = Medium
+		} else {
+			d.Confidence = Low
+		}
+	}
+	return d
+}
+
+func first(s, v string) string {
+	if s != "" {
+		return s
+	}
+	return v
+}
+
+// parse008 returns the range of years given by the date type and dates in
+// 008, and reports whether the dates are exact.
+func parse008(dateType, date1, date2 string) (int, int, bool, bool) {
+	s1, e1, exact1, ok1 := parseYear008(date1)
+	if !ok1 {
+		return 0, 0, false, false
+	}
+	if !rangeTypes[dateType] {
+		return s1, e1, exact1, true
+	}
+	if date2 == "9999" {
+		return s1, 0, exact1, true
+	}
+	_, e2, exact2, ok2 := parseYear008(date2)
+	if !ok2 {
+		return s1, e1, false, true
+	}
+	if e2 < s1 {
+		return s1, e1, false, true
+	}
+	return s1, e2, exact1 && exact2 && dateType != "q", true
+}
+
+// parseYear008 parses a year in 008, which may contain "u" for unknown digits,
+// e.g. "19uu".
+func parseYear008(s string) (int, int, bool, bool) {
+	if len(s) != 4 {
+		return 0, 0, false, false
+	}
+	n := strings.IndexByte(s, 'u')
+	if n < 0 {
+		y, err := strconv.Atoi(s)
+		if err != nil || y == 0 || y == 9999 {
+			return 0, 0, false, false
+		}
+		return y, y, true, true
+	}
+	if n == 0 || strings.Trim(s[n:], "u") != "" {
+		return 0, 0, false, false
+	}
+	p, err := strconv.Atoi(s[:n])
+	if err != nil {
+		return 0, 0, false, false
+	}
+	scale := 1
+	for i := n; i < 4; i++ {
+		scale *= 10
+	}
+	return p * scale, p*scale + scale - 1, false, true
+}
+
+var (
+	yearRangePattern = regexp.MustCompile(`(?:^|\D)(\d{4})\s*-\s*(\d{4})(?:\D|$)`)
+	yearPattern      = regexp.MustCompile(`(?:^|\D)(\d{4})(?:\D|$)`)
+	decadePattern    = regexp.MustCompile(`(?:^|\D)(\d{3})[-u_?]`)
+	centuryPattern   = regexp.MustCompile(`(?:^|\D)(\d{2})(?:--|uu|__)`)
+	romanPattern     = regexp.MustCompile(`\b[MDCLXVI]{3,}\b`)
+)
+
+// ParseStatement parses a date of publication such as "c1998", "[199-?]", or
+// "MCMXCVIII" in 260 $c or 264 $c, and returns the range of years and
+// whether the date is exact.
+func ParseStatement(s string) (int, int, bool, bool) {
+	questionable := strings.Contains(s, "?")
+	if m := yearRangePattern.FindStringSubmatch(s); m != nil {
+		start, _ := strconv.Atoi(m[1])
+		end, _ := strconv.Atoi(m[2])
+		if end >= start {
+			return start, end, !questionable, true
+		}
+	}
+	if m := yearPattern.FindStringSubmatch(s); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		return y, y, !questionable, true
+	}
+	if m := decadePattern.FindStringSubmatch(s); m != nil {
+		d, _ := strconv.Atoi(m[1])
+		return d * 10, d*10 + 9, false, true
+	}
+	if m := centuryPattern.FindStringSubmatch(s); m != nil {
+		c, _ := strconv.Atoi(m[1])
+		return c * 100, c*100 + 99, false, true
+	}
+	if m := romanPattern.FindString(s); m != "" {
+		if y := roman(m); y >= 1000 && y < 2100 {
+			return y, y, !questionable, true
+		}
+	}
+	return 0, 0, false, false
+}
+
+var romanValues = map[byte]int{'I': 1, 'V': 5, 'X': 10, 'L': 50, 'C': 100, 'D': 500, 'M': 1000}
+
+// roman returns the value of a Roman numeral.
+func roman(s string) int {
+	var n int
+	for i := 0; i < len(s); i++ {
+		v := romanValues[s[i]]
+		if i+1 < len(s) && romanValues[s[i+1]] > v {
+			n -= v
+		} else {
+			n += v
+		}
+	}
+	return n
+}
@@ -0,0 +1,81 @@
+package pubdate
+
+import (
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+func TestParseStatement(t *testing.T) {
+	tests := []struct {
+		s          string
+		start, end int
+		exact, ok  bool
+	}{
+		{"1998", 1998, 1998, true, true},
+		{"c1998.", 1998, 1998, true, true},
+		{"[1998]", 1998, 1998, true, true},
+		{"[1998?]", 1998, 1998, false, true},
+		{"1998-2001.", 1998, 2001, true, true},
+		{"2001-1999", 2001, 2001, true, true},
+		{"[199-?]", 1990, 1999, false, true},
+		{"[199-]", 1990, 1999, false, true},
+		{"[19--]", 1900, 1999, false, true},
+		{"MCMXCVIII", 1998, 1998, true, true},
+		{"Anno MDCCLXXVI.", 1776, 1776, true, true},
+		{"[MCMXCVIII?]", 1998, 1998, false, true},
+		{"XIV", 0, 0, false, false},
+		{"n.d.", 0, 0, false, false},
+		{"", 0, 0, false, false},
+	}
+	for _, tt := range tests {
+		start, end, exact, ok := ParseStatement(tt.s)
+		if start != tt.start || end != tt.end || exact != tt.exact || ok != tt.ok {
+			t.Errorf("ParseStatement(%q) = %d, %d, %v, %v, want %d, %d, %v, %v", tt.s, start, end, exact, ok,
+				tt.start, tt.end, tt.exact, tt.ok)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	// f008 returns an 008 field with the given date type and dates.
+	f008 := func(dates string) srs.Marc {
+		return srs.Marc{Field: "008", Content: "980101" + dates + "nyu           000 0 eng d"}
+	}
+	c := func(field, ind2, content string) srs.Marc {
+		return srs.Marc{Field: field, Ind2: ind2, SF: "c", Content: content}
+	}
+	tests := []struct {
+		name  string
+		mrecs []srs.Marc
+		want  Date
+	}{
+		{"single year agrees", []srs.Marc{f008("s1998    "), c("260", " ", "c1998.")},
+			Date{DateType: "s", YearStart: 1998, YearEnd: 1998, Confidence: High, Source: "008"}},
+		{"single year disagrees", []srs.Marc{f008("s1998    "), c("260", " ", "2001.")},
+			Date{DateType: "s", YearStart: 1998, YearEnd: 1998, Confidence: Medium, Source: "008"}},
+		{"unknown digits", []srs.Marc{f008("s19uu    ")},
+			Date{DateType: "s", YearStart: 1900, YearEnd: 1999, Confidence: Low, Source: "008"}},
+		{"range", []srs.Marc{f008("d19802001")},
+			Date{DateType: "d", YearStart: 1980, YearEnd: 2001, Confidence: High, Source: "008"}},
+		{"currently published", []srs.Marc{f008("c19859999")},
+			Date{DateType: "c", YearStart: 1985, YearEnd: 0, Confidence: High, Source: "008"}},
+		{"questionable", []srs.Marc{f008("q19801989")},
+			Date{DateType: "q", YearStart: 1980, YearEnd: 1989, Confidence: Low, Source: "008"}},
+		{"264 publication preferred", []srs.Marc{f008("|||||||||"), c("264", "4", "©2003"),
+			c("264", "1", "[2005?]")},
+			Date{DateType: "|", YearStart: 2005, YearEnd: 2005, Confidence: Low, Source: "264"}},
+		{"260 only", []srs.Marc{c("260", " ", "MCMXCVIII")},
+			Date{YearStart: 1998, YearEnd: 1998, Confidence: Medium, Source: "260"}},
+		{"no date", []srs.Marc{c("260", " ", "[n.d.]")}, Date{}},
+		{"non-ASCII 008", []srs.Marc{{Field: "008", Content: "9801ñ1s1998    xx            000 0 fré d"}},
+			Date{DateType: "s", YearStart: 1998, YearEnd: 1998, Confidence: High, Source: "008"}},
+		{"short 008", []srs.Marc{{Field: "008", Content: "9801ñ1s1998"}, c("260", " ", "1999.")},
+			Date{YearStart: 1999, YearEnd: 1999, Confidence: Medium, Source: "260"}},
+	}
+	for _, tt := range tests {
+		if got := Extract(tt.mrecs); got != tt.want {
+			t.Errorf("%s: Extract() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package srs
+
+// This file contains the json.Decoder implementation of Transform that was
+// replaced by Parser.  It is retained as a reference for testing that Parser
+// produces identical output, and for comparison in benchmarks.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/uuid"
+)
+
+// decoderTransform is the previous implementation of Transform.
+func decoderTransform(marcjson *string, state, recordType string, filter *Filter) ([]Marc, string, []Anomaly, error) {
+	p := decoder{dec: json.NewDecoder(strings.NewReader(*marcjson)), line: 1, fieldCounts: make(map[string]int16)}
+	if err := p.parseRecord(); err != nil {
+		return nil, "", nil, err
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, "", nil, fmt.Errorf("parsing error")
+	}
+	mrecs := p.mrecs
+	// Extract the instance identifier from 999$i (f f).
+	instanceID, err := getInstanceID(mrecs)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("parsing: %v", err)
+	}
+	// If the MARC record is not selected, return nothing.
+	if !filter.Match(state, recordType, instanceID) {
+		return []Marc{}, uuid.NilUUID, nil, nil
+	}
+	if instanceID == "" {
+		instanceID = uuid.NilUUID
+	}
+	return mrecs, instanceID, p.anomalies, nil
+}
+
+// decoder reads an SRS MARC record as a stream of JSON tokens.
+type decoder struct {
+	dec *json.Decoder
+	// mrecs is the slice of Marc structs that will contain the transformed
+	// rows.
+	mrecs       []Marc
+	anomalies   []Anomaly
+	line        int16
+	fieldCounts map[string]int16
+}
+
+func (p *decoder) parseRecord() error {
+	if err := p.delim('{'); err != nil {
+		return fmt.Errorf("parsing error")
+	}
+	var leader string
+	var leaderFound, fieldsFound bool
+	// leaderRows lists the indexes of rows containing the leader, which is
+	// output before 001 but may appear after the fields.
+	var leaderRows []int
+	for p.dec.More() {
+		key, err := p.key()
+		if err != nil {
+			return fmt.Errorf("parsing error")
+		}
+		switch key {
+		case "leader":
+			t, err := p.dec.Token()
+			if err != nil {
+				return fmt.Errorf("parsing: %s", err)
+			}
+			var ok bool
+			if leader, ok = t.(string); !ok {
+				return fmt.Errorf("parsing: \"leader\" is not a string")
+			}
+			leaderFound = true
+		case "fields":
+			if err = p.delim('['); err != nil {
+				return fmt.Errorf("parsing: \"fields\" is not an array")
+			}
+			for p.dec.More() {
+				if err = p.parseField(&leaderRows); err != nil {
+					return fmt.Errorf("parsing: %s", err)
+				}
+			}
+			if _, err = p.dec.Token(); err != nil {
+				return fmt.Errorf("parsing: %s", err)
+			}
+			fieldsFound = true
+		default:
+			if err = p.skip(); err != nil {
+				return fmt.Errorf("parsing: %s", err)
+			}
+		}
+	}
+	if _, err := p.dec.Token(); err != nil {
+		return fmt.Errorf("parsing: %s", err)
+	}
+	if !leaderFound {
+		return fmt.Errorf("parsing: \"leader\" not found")
+	}
+	if !fieldsFound {
+		return fmt.Errorf("parsing: \"fields\" not found")
+	}
+	for _, i := range leaderRows {
+		p.mrecs[i].Content = leader
+	}
+	return nil
+}
+
+// parseField reads an element of the fields array, which is an object with a
+// MARC tag as its key.
+func (p *decoder) parseField(leaderRows *[]int) error {
+	if err := p.delim('{'); err != nil {
+		return fmt.Errorf("\"fields\" element is not an object")
+	}
+	var n int
+	for p.dec.More() {
+		t, err := p.key()
+		if err != nil {
+			return err
+		}
+		fieldC := p.fieldCounts[t] + 1
+		p.fieldCounts[t] = fieldC
+		if n++; n == 2 {
+			p.anomalies = append(p.anomalies, Anomaly{Field: t, Ord: fieldC,
+				Message: "\"fields\" element has more than one key"})
+		}
+		tok, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch v := tok.(type) {
+		case string:
+			// We convert a string field to a single row of output.
+			if t == "001" {
+				// When we encounter 001, we first output the leader
+				// as 000.  Its content is filled in after the whole
+				// record has been read.
+				*leaderRows = append(*leaderRows, len(p.mrecs))
+				p.append(Marc{Field: "000", Ord: fieldC})
+			}
+			// Now write the row.
+			p.append(Marc{Field: t, Ord: fieldC, Content: v})
+		case json.Delim:
+			if v != '{' {
+				return fmt.Errorf("unknown data type in field \"" + t + "\"")
+			}
+			// An object needs further processing, which will output
+			// one or more rows.
+			if err = p.parseSubfields(t, fieldC); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown data type in field \"" + t + "\"")
+		}
+	}
+	_, err := p.dec.Token()
+	return err
+}
+
+// parseSubfields reads the object containing the indicators and subfields of
+// a data field, after its opening delimiter.
+func (p *decoder) parseSubfields(field string, ord int16) error {
+	var ind1, ind2 string
+	var ind1Found, ind2Found, subfieldsFound bool
+	// The subfields are output after the indicators have been read.
+	var sfs []Marc
+	for p.dec.More() {
+		key, err := p.key()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "ind1", "ind2":
+			t, err := p.dec.Token()
+			if err != nil {
+				return err
+			}
+			s, ok := t.(string)
+			if !ok {
+				return fmt.Errorf("\"%s\" wrong type", key)
+			}
+			if key == "ind1" {
+				ind1, ind1Found = s, true
+			} else {
+				ind2, ind2Found = s, true
+			}
+		case "subfields":
+			if err = p.delim('['); err != nil {
+				return fmt.Errorf("\"subfields\" is not an array")
+			}
+			for p.dec.More() {
+				if err = p.delim('{'); err != nil {
+					return fmt.Errorf("\"subfields\" element is not an object")
+				}
+				var n int
+				for p.dec.More() {
+					k, err := p.key()
+					if err != nil {
+						return err
+					}
+					if n++; n == 2 {
+						p.anomalies = append(p.anomalies, Anomaly{Field: field, Ord: ord,
+							Message: "\"subfields\" element has more than one key"})
+					}
+					t, err := p.dec.Token()
+					if err != nil {
+						return err
+					}
+					vs, ok := t.(string)
+					if !ok {
+						return fmt.Errorf("subfield value is not a string")
+					}
+					sfs = append(sfs, Marc{SF: k, Content: vs})
+				}
+				if _, err = p.dec.Token(); err != nil {
+					return err
+				}
+			}
+			if _, err = p.dec.Token(); err != nil {
+				return err
+			}
+			subfieldsFound = true
+		default:
+			if err = p.skip(); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := p.dec.Token(); err != nil {
+		return err
+	}
+	switch {
+	case !ind1Found:
+		return fmt.Errorf("\"ind1\" not found")
+	case !ind2Found:
+		return fmt.Errorf("\"ind2\" not found")
+	case !subfieldsFound:
+		return fmt.Errorf("\"subfields\" not found")
+	}
+	for _, sf := range sfs {
+		p.append(Marc{Field: field, Ind1: ind1, Ind2: ind2, Ord: ord, SF: sf.SF, Content: sf.Content})
+	}
+	return nil
+}
+
+// append adds a row, assigning its line number.
+func (p *decoder) append(m Marc) {
+	m.Line = p.line
+	p.mrecs = append(p.mrecs, m)
+	p.line++
+}
+
+// delim reads a token and checks that it is the delimiter d.
+func (p *decoder) delim(d json.Delim) error {
+	t, err := p.dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != d {
+		return fmt.Errorf("expected %q", d)
+	}
+	return nil
+}
+
+// key reads an object key.
+func (p *decoder) key() (string, error) {
+	t, err := p.dec.Token()
+	if err != nil {
+		return "", err
+	}
+	k, ok := t.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key")
+	}
+	return k, nil
+}
+
+// skip reads and discards a value.
+func (p *decoder) skip() error {
+	var depth int
+	for {
+		t, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package srs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Filter selects the SRS records to be transformed.  The same Filter is used
+// to select records in Go (Match) and in SQL (RecordSQL and IDSQL), so that
+// the two remain consistent.
+type Filter struct {
+	// States lists the record states to be selected.
+	States []string
+	// RecordTypes lists the record types to be selected.  A record having
+	// no record type is considered to be a bibliographic record.
+	RecordTypes []string
+	// RequireID requires some content in 999$i (ind1 and ind2 = "f"),
+	// which is presumed to be the FOLIO identifier.
+	RequireID bool
+}
+
+// NewFilter returns the default Filter for a record type, which selects
+// records having state = "ACTUAL", the record type, and an identifier.
+func NewFilter(recordType string) *Filter {
+	return &Filter{
+		States:      []string{"ACTUAL"},
+		RecordTypes: []string{recordType},
+		RequireID:   true,
+	}
+}
+
+// Match reports whether a record having state, recordType, and identifier id
+// (from 999$i) is selected.
+func (f *Filter) Match(state, recordType, id string) bool {
+	if recordType == "" {
+		recordType = Bib
+	}
+	return contains(f.States, state) && contains(f.RecordTypes, recordType) && (!f.RequireID || id != "")
+}
+
+// NonActual reports whether records having a state other than "ACTUAL" may
+// be selected.
+func (f *Filter) NonActual() bool {
+	for _, s := range f.States {
+		if s != "ACTUAL" {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordSQL returns an SQL condition on the SRS records table aliased as r,
+// equivalent to Match without the identifier requirement.
+func (f *Filter) RecordSQL() string {
+	return "r.state::text IN (" + sqlList(f.States) + ") AND coalesce(r.record_type::text, '" + Bib +
+		"') IN (" + sqlList(f.RecordTypes) + ")"
+}
+
+// IDSQL returns an SQL condition on an output table aliased as mt, selecting
+// the rows that satisfy the identifier requirement.  It should match
+// getInstanceID.
+func (f *Filter) IDSQL() string {
+	if !f.RequireID {
+		return "true"
+	}
+	return "mt.field = '999' AND mt.sf = 'i' AND mt.ind1 = 'f' AND mt.ind2 = 'f' AND mt.content <> ''"
+}
+
+// String returns a description of the filter which can be compared with a
+// previous one.
+func (f *Filter) String() string {
+	return "states=" + strings.Join(f.States, ",") + ";record_types=" + strings.Join(f.RecordTypes, ",") +
+		";require_id=" + strconv.FormatBool(f.RequireID)
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sqlList(list []string) string {
+	q := make([]string, 0, len(list))
+	for _, s := range list {
+		q = append(q, "'"+strings.ReplaceAll(s, "'", "''")+"'")
+	}
+	return strings.Join(q, ",")
+}
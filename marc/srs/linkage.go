@@ -0,0 +1,133 @@
+package srs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Linkage is the content of subfield $6, which links a field to an 880
+// field containing an alternate graphic representation, or an 880 field to
+// the field it parallels.
+type Linkage struct {
+	// Tag is the linking tag.
+	Tag string
+	// Occurrence is the occurrence number, which is "00" if there is no
+	// linked field.
+	Occurrence string
+	// Script is the script identification code, e.g. "(3" for Arabic.
+	Script string
+	// Orientation is the field orientation code, "r" for right-to-left, or
+	// "" if the field is left-to-right.
+	Orientation string
+}
+
+// ParseLinkage parses the content of subfield $6, e.g. "245-01/$1" or
+// "880-02/(3/r".  It reports false if the content is not valid.
+func ParseLinkage(s string) (Linkage, bool) {
+	var l Linkage
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	tag, occ, ok := strings.Cut(parts[0], "-")
+	if !ok || len(tag) != 3 || len(occ) < 2 || !isDigits(tag) || !isDigits(occ) {
+		return l, false
+	}
+	l.Tag = tag
+	l.Occurrence = occ
+	if len(parts) > 1 {
+		l.Script = parts[1]
+	}
+	if len(parts) > 2 {
+		l.Orientation = parts[2]
+	}
+	return l, true
+}
+
+// AltGraphicLink links an occurrence of an 880 field to the field it
+// parallels.
+type AltGraphicLink struct {
+	// Ord is the occurrence of the 880 field.
+	Ord int16
+	Linkage
+	// LinkedOrd is the occurrence of the linked field, or 0 if the linked
+	// field is not found.
+	LinkedOrd int16
+}
+
+// AltGraphicLinks returns the links of 880 fields in the rows of a
+// transformed record.  The field paralleled by an 880 field is found by its
+// tag and its own $6, which links back to the 880 field with the same
+// occurrence number.
+func AltGraphicLinks(mrecs []Marc) []AltGraphicLink {
+	type target struct {
+		tag string
+		occ string
+	}
+	linked := make(map[target]int16)
+	var links []AltGraphicLink
+	for _, m := range mrecs {
+		if m.SF != "6" {
+			continue
+		}
+		l, ok := ParseLinkage(m.Content)
+		if !ok {
+			continue
+		}
+		if m.Field == "880" {
+			links = append(links, AltGraphicLink{Ord: m.Ord, Linkage: l})
+			continue
+		}
+		if l.Tag == "880" && l.Occurrence != "00" {
+			t := target{tag: m.Field, occ: l.Occurrence}
+			if _, ok := linked[t]; !ok {
+				linked[t] = m.Ord
+			}
+		}
+	}
+	for i, l := range links {
+		if l.Occurrence != "00" {
+			links[i].LinkedOrd = linked[target{tag: l.Tag, occ: l.Occurrence}]
+		}
+	}
+	return links
+}
+
+// FieldLink is the content of subfield $8, which links fields within a
+// record and may give their sequence.
+type FieldLink struct {
+	// Link is the link number.
+	Link int32
+	// Sequence is the sequence number, or -1 if not present.
+	Sequence int32
+	// Type is the field link type code, e.g. "a" for action or "" if not
+	// present.
+	Type string
+}
+
+// ParseFieldLink parses the content of subfield $8, e.g. "1.5\a" or "3".  It
+// reports false if the content is not valid.
+func ParseFieldLink(s string) (FieldLink, bool) {
+	l := FieldLink{Sequence: -1}
+	s, l.Type, _ = strings.Cut(strings.TrimSpace(s), "\\")
+	link, seq, hasSeq := strings.Cut(s, ".")
+	n, err := strconv.ParseInt(link, 10, 32)
+	if err != nil || n < 0 {
+		return l, false
+	}
+	l.Link = int32(n)
+	if hasSeq {
+		n, err = strconv.ParseInt(seq, 10, 32)
+		if err != nil || n < 0 {
+			return l, false
+		}
+		l.Sequence = int32(n)
+	}
+	return l, true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
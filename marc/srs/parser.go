@@ -0,0 +1,573 @@
+package srs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/library-data-platform/ldpmarc/marc/uuid"
+)
+
+// Parser transforms SRS MARC records in JSON format.  It reads the JSON data
+// directly, without decoding them into generic values, and reuses its
+// buffers from one record to the next.  A Parser is not safe for concurrent
+// use.
+type Parser struct {
+	// Lenient enables repair of fields and subfields that are malformed,
+	// which otherwise cause the record to be rejected.  Malformed fields
+	// and subfields are skipped, and missing indicators are replaced with
+	// blanks.  Each repair is reported as an anomaly.  A record is still
+	// rejected if it is not valid JSON or if its leader or fields array is
+	// missing or malformed.
+	Lenient bool
+	sc      scanner
+	// mrecs is the slice of Marc structs that will contain the
+	// transformed rows.
+	mrecs       []Marc
+	anomalies   []Anomaly
+	sfs         []Marc
+	leaderRows  []int
+	leader      string
+	line        int16
+	fieldCounts map[string]int16
+}
+
+// NewParser returns a new Parser.
+func NewParser() *Parser {
+	return &Parser{fieldCounts: make(map[string]int16)}
+}
+
+// Leader returns the leader of the record most recently transformed by p,
+// whether or not the record has a 001 field, or "" if the record could not
+// be parsed.  Like the rows, it remains valid only until the next call to
+// Transform.
+func (p *Parser) Leader() string {
+	return p.leader
+}
+
+// Transform is like the Transform function, except that the returned slices
+// are reused by the next call to Transform and remain valid only until then.
+// In addition, strings in the returned rows may share memory with *marcjson,
+// so that retaining one of them retains the whole record; a value that is
+// kept after the record has been processed should be copied with
+// strings.Clone.
+func (p *Parser) Transform(marcjson *string, state, recordType string, filter *Filter) ([]Marc, string, []Anomaly, error) {
+	p.sc = scanner{s: *marcjson}
+	p.mrecs = p.mrecs[:0]
+	p.anomalies = p.anomalies[:0]
+	p.leaderRows = p.leaderRows[:0]
+	p.leader = ""
+	p.line = 1
+	for k := range p.fieldCounts {
+		delete(p.fieldCounts, k)
+	}
+	if err := p.parseRecord(); err != nil {
+		return nil, "", nil, err
+	}
+	if p.sc.peek() != 0 {
+		return nil, "", nil, fmt.Errorf("parsing error")
+	}
+	mrecs := p.mrecs
+	// Extract the instance identifier from 999$i (f f).
+	instanceID, err := getInstanceID(mrecs)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("parsing: %v", err)
+	}
+	// If the MARC record is not selected, return nothing.
+	if !filter.Match(state, recordType, instanceID) {
+		return []Marc{}, uuid.NilUUID, nil, nil
+	}
+	if instanceID == "" {
+		instanceID = uuid.NilUUID
+	}
+	return mrecs, instanceID, p.anomalies, nil
+}
+
+func (p *Parser) parseRecord() error {
+	sc := &p.sc
+	if !sc.consume('{') {
+		return fmt.Errorf("parsing error")
+	}
+	var leader string
+	var leaderFound, fieldsFound bool
+	first := true
+	for {
+		more, err := sc.next('}', &first)
+		if err != nil {
+			return fmt.Errorf("parsing error")
+		}
+		if !more {
+			break
+		}
+		key, err := sc.key()
+		if err != nil {
+			return fmt.Errorf("parsing error")
+		}
+		switch key {
+		case "leader":
+			if sc.peek() != '"' {
+				return fmt.Errorf("parsing: \"leader\" is not a string")
+			}
+			if leader, err = sc.str(); err != nil {
+				return fmt.Errorf("parsing: %s", err)
+			}
+			leaderFound = true
+		case "fields":
+			if !sc.consume('[') {
+				return fmt.Errorf("parsing: \"fields\" is not an array")
+			}
+			afirst := true
+			for {
+				more, err := sc.next(']', &afirst)
+				if err != nil {
+					return fmt.Errorf("parsing: %s", err)
+				}
+				if !more {
+					break
+				}
+				if err = p.parseField(); err != nil {
+					return fmt.Errorf("parsing: %s", err)
+				}
+			}
+			fieldsFound = true
+		default:
+			if err = sc.skip(); err != nil {
+				return fmt.Errorf("parsing: %s", err)
+			}
+		}
+	}
+	if !leaderFound {
+		return fmt.Errorf("parsing: \"leader\" not found")
+	}
+	if !fieldsFound {
+		return fmt.Errorf("parsing: \"fields\" not found")
+	}
+	p.leader = leader
+	// The leader is output before 001 but may appear after the fields.
+	for _, i := range p.leaderRows {
+		p.mrecs[i].Content = leader
+	}
+	return nil
+}
+
+// parseField reads an element of the fields array, which is an object with a
+// MARC tag as its key.
+func (p *Parser) parseField() error {
+	sc := &p.sc
+	if !sc.consume('{') {
+		if err := p.repair("", 0, "\"fields\" element is not an object", "element skipped"); err != nil {
+			return err
+		}
+		return sc.skip()
+	}
+	var n int
+	first := true
+	for {
+		more, err := sc.next('}', &first)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+		t, err := sc.key()
+		if err != nil {
+			return err
+		}
+		fieldC := p.fieldCounts[t] + 1
+		p.fieldCounts[t] = fieldC
+		if n++; n == 2 {
+			p.anomalies = append(p.anomalies, Anomaly{Field: t, Ord: fieldC,
+				Message: "\"fields\" element has more than one key"})
+		}
+		switch sc.peek() {
+		case '"':
+			// We convert a string field to a single row of output.
+			v, err := sc.str()
+			if err != nil {
+				return err
+			}
+			if t == "001" {
+				// When we encounter 001, we first output the leader
+				// as 000.  Its content is filled in after the whole
+				// record has been read.
+				p.leaderRows = append(p.leaderRows, len(p.mrecs))
+				p.append(Marc{Field: "000", Ord: fieldC})
+			}
+			// Now write the row.
+			p.append(Marc{Field: t, Ord: fieldC, Content: v})
+		case '{':
+			// An object needs further processing, which will output
+			// one or more rows.
+			sc.pos++
+			if err = p.parseSubfields(t, fieldC); err != nil {
+				return err
+			}
+		default:
+			if err = p.repair(t, fieldC, "unknown data type in field \""+t+"\"", "field skipped"); err != nil {
+				return err
+			}
+			if err = sc.skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseSubfields reads the object containing the indicators and subfields of
+// a data field, after its opening delimiter.
+func (p *Parser) parseSubfields(field string, ord int16) error {
+	sc := &p.sc
+	var ind1, ind2 string
+	var ind1Found, ind2Found, subfieldsFound, skipped bool
+	// The subfields are output after the indicators have been read.
+	p.sfs = p.sfs[:0]
+	first := true
+	for {
+		more, err := sc.next('}', &first)
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+		key, err := sc.key()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "ind1", "ind2":
+			var s string
+			if sc.peek() == '"' {
+				if s, err = sc.str(); err != nil {
+					return err
+				}
+			} else {
+				if err = p.repair(field, ord, "\""+key+"\" wrong type", "blank substituted"); err != nil {
+					return err
+				}
+				if err = sc.skip(); err != nil {
+					return err
+				}
+				s = " "
+			}
+			if key == "ind1" {
+				ind1, ind1Found = s, true
+			} else {
+				ind2, ind2Found = s, true
+			}
+		case "subfields":
+			subfieldsFound = true
+			if !sc.consume('[') {
+				if err = p.repair(field, ord, "\"subfields\" is not an array", "field skipped"); err != nil {
+					return err
+				}
+				if err = sc.skip(); err != nil {
+					return err
+				}
+				p.sfs = p.sfs[:0]
+				skipped = true
+				continue
+			}
+			if err = p.parseSubfieldArray(field, ord); err != nil {
+				return err
+			}
+		default:
+			if err = sc.skip(); err != nil {
+				return err
+			}
+		}
+	}
+	if !ind1Found {
+		if err := p.repair(field, ord, "\"ind1\" not found", "blank substituted"); err != nil {
+			return err
+		}
+		ind1 = " "
+	}
+	if !ind2Found {
+		if err := p.repair(field, ord, "\"ind2\" not found", "blank substituted"); err != nil {
+			return err
+		}
+		ind2 = " "
+	}
+	if !subfieldsFound {
+		return p.repair(field, ord, "\"subfields\" not found", "field skipped")
+	}
+	if skipped {
+		return nil
+	}
+	for _, sf := range p.sfs {
+		p.append(Marc{Field: field, Ind1: ind1, Ind2: ind2, Ord: ord, SF: sf.SF, Content: sf.Content})
+	}
+	return nil
+}
+
+// parseSubfieldArray reads the subfields array, after its opening delimiter.
+func (p *Parser) parseSubfieldArray(field string, ord int16) error {
+	sc := &p.sc
+	afirst := true
+	for {
+		more, err := sc.next(']', &afirst)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+		if !sc.consume('{') {
+			if err = p.repair(field, ord, "\"subfields\" element is not an object", "subfield skipped"); err != nil {
+				return err
+			}
+			if err = sc.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		var n int
+		first := true
+		for {
+			more, err := sc.next('}', &first)
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+			k, err := sc.key()
+			if err != nil {
+				return err
+			}
+			if n++; n == 2 {
+				p.anomalies = append(p.anomalies, Anomaly{Field: field, Ord: ord,
+					Message: "\"subfields\" element has more than one key"})
+			}
+			if sc.peek() != '"' {
+				if err = p.repair(field, ord, "subfield value is not a string", "subfield skipped"); err != nil {
+					return err
+				}
+				if err = sc.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			vs, err := sc.str()
+			if err != nil {
+				return err
+			}
+			p.sfs = append(p.sfs, Marc{SF: k, Content: vs})
+		}
+	}
+}
+
+// repair records an anomaly that is repaired by a lenient Parser.  If the
+// Parser is not lenient, the anomaly is returned as an error.
+func (p *Parser) repair(field string, ord int16, message, repair string) error {
+	if !p.Lenient {
+		return errors.New(message)
+	}
+	p.anomalies = append(p.anomalies, Anomaly{Field: field, Ord: ord, Message: message, Repair: repair})
+	return nil
+}
+
+// append adds a row, assigning its line number.
+func (p *Parser) append(m Marc) {
+	m.Line = p.line
+	p.mrecs = append(p.mrecs, m)
+	p.line++
+}
+
+// scanner reads JSON tokens from a string.
+type scanner struct {
+	s   string
+	pos int
+}
+
+func (sc *scanner) syntaxError() error {
+	return fmt.Errorf("invalid JSON at offset %d", sc.pos)
+}
+
+// peek skips white space and returns the next byte, or 0 at the end of the
+// input.
+func (sc *scanner) peek() byte {
+	for sc.pos < len(sc.s) {
+		switch c := sc.s[sc.pos]; c {
+		case ' ', '\t', '\n', '\r':
+			sc.pos++
+		default:
+			return c
+		}
+	}
+	return 0
+}
+
+// consume reads the byte c if it is next.
+func (sc *scanner) consume(c byte) bool {
+	if sc.peek() == c {
+		sc.pos++
+		return true
+	}
+	return false
+}
+
+// next reads the separator before the next element of an object or array,
+// or the closing delimiter, in which case it reports false.  The variable
+// pointed to by first is true before the first element.
+func (sc *scanner) next(close byte, first *bool) (bool, error) {
+	if sc.consume(close) {
+		return false, nil
+	}
+	if *first {
+		*first = false
+		return true, nil
+	}
+	if !sc.consume(',') {
+		return false, sc.syntaxError()
+	}
+	return true, nil
+}
+
+// key reads an object key and the following colon.
+func (sc *scanner) key() (string, error) {
+	if sc.peek() != '"' {
+		return "", sc.syntaxError()
+	}
+	k, err := sc.str()
+	if err != nil {
+		return "", err
+	}
+	if !sc.consume(':') {
+		return "", sc.syntaxError()
+	}
+	return k, nil
+}
+
+// str reads a string.  If the string contains no escape sequences and is
+// valid UTF-8, it is returned as a substring of the input without copying.
+func (sc *scanner) str() (string, error) {
+	if !sc.consume('"') {
+		return "", sc.syntaxError()
+	}
+	start := sc.pos
+	simple := true
+	for sc.pos < len(sc.s) {
+		c := sc.s[sc.pos]
+		switch {
+		case c == '"':
+			sc.pos++
+			if simple {
+				return sc.s[start : sc.pos-1], nil
+			}
+			// Escape sequences and invalid UTF-8 are decoded by the
+			// standard library, which replaces invalid bytes.
+			var v string
+			if err := json.Unmarshal([]byte(sc.s[start-1:sc.pos]), &v); err != nil {
+				return "", err
+			}
+			return v, nil
+		case c == '\\':
+			simple = false
+			sc.pos += 2
+		case c < 0x20:
+			return "", sc.syntaxError()
+		case c < utf8.RuneSelf:
+			sc.pos++
+		default:
+			r, size := utf8.DecodeRuneInString(sc.s[sc.pos:])
+			if r == utf8.RuneError && size == 1 {
+				simple = false
+			}
+			sc.pos += size
+		}
+	}
+	return "", sc.syntaxError()
+}
+
+// skip reads and discards a value.
+func (sc *scanner) skip() error {
+	switch c := sc.peek(); c {
+	case '"':
+		_, err := sc.str()
+		return err
+	case '{', '[':
+		close := byte('}')
+		if c == '[' {
+			close = ']'
+		}
+		sc.pos++
+		first := true
+		for {
+			more, err := sc.next(close, &first)
+			if err != nil {
+				return err
+			}
+			if !more {
+				return nil
+			}
+			if c == '{' {
+				if _, err = sc.key(); err != nil {
+					return err
+				}
+			}
+			if err = sc.skip(); err != nil {
+				return err
+			}
+		}
+	case 't':
+		return sc.literal("true")
+	case 'f':
+		return sc.literal("false")
+	case 'n':
+		return sc.literal("null")
+	default:
+		return sc.number()
+	}
+}
+
+func (sc *scanner) literal(lit string) error {
+	if len(sc.s)-sc.pos < len(lit) || sc.s[sc.pos:sc.pos+len(lit)] != lit {
+		return sc.syntaxError()
+	}
+	sc.pos += len(lit)
+	return nil
+}
+
+// number reads a number as defined by the JSON grammar:  an optional minus
+// sign, an integer without leading zeros, an optional fraction, and an
+// optional exponent.
+func (sc *scanner) number() error {
+	if sc.pos < len(sc.s) && sc.s[sc.pos] == '-' {
+		sc.pos++
+	}
+	switch {
+	case sc.pos < len(sc.s) && sc.s[sc.pos] == '0':
+		sc.pos++
+	case !sc.digits():
+		return sc.syntaxError()
+	}
+	if sc.pos < len(sc.s) && sc.s[sc.pos] == '.' {
+		sc.pos++
+		if !sc.digits() {
+			return sc.syntaxError()
+		}
+	}
+	if sc.pos < len(sc.s) && (sc.s[sc.pos] == 'e' || sc.s[sc.pos] == 'E') {
+		sc.pos++
+		if sc.pos < len(sc.s) && (sc.s[sc.pos] == '+' || sc.s[sc.pos] == '-') {
+			sc.pos++
+		}
+		if !sc.digits() {
+			return sc.syntaxError()
+		}
+	}
+	return nil
+}
+
+// digits reads one or more decimal digits and reports whether any were read.
+func (sc *scanner) digits() bool {
+	start := sc.pos
+	for sc.pos < len(sc.s) && sc.s[sc.pos] >= '0' && sc.s[sc.pos] <= '9' {
+		sc.pos++
+	}
+	return sc.pos > start
+}
@@ -0,0 +1,153 @@
+package srs
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readFixtures returns the records in testdata/records.jsonl, one per line.
+func readFixtures(t testing.TB) []string {
+	f, err := os.Open("testdata/records.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var recs []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			recs = append(recs, line)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return recs
+}
+
+// TestParserFixtures checks that Parser produces the same output as the
+// json.Decoder implementation for each fixture.  A single Parser is used for
+// all records, so that reuse of its buffers is also tested.
+func TestParserFixtures(t *testing.T) {
+	noID := NewFilter(Bib)
+	noID.RequireID = false
+	p := NewParser()
+	for _, filter := range []*Filter{NewFilter(Bib), noID} {
+		for i, rec := range readFixtures(t) {
+			rec := rec
+			want, wantID, wantAnomalies, wantErr := decoderTransform(&rec, "ACTUAL", "", filter)
+			got, gotID, gotAnomalies, gotErr := p.Transform(&rec, "ACTUAL", "", filter)
+			if (gotErr != nil) != (wantErr != nil) {
+				t.Errorf("record %d: error %v, want %v", i+1, gotErr, wantErr)
+				continue
+			}
+			if wantErr != nil {
+				continue
+			}
+			if len(want) == 0 && len(got) == 0 {
+				want, got = nil, nil
+			}
+			if len(wantAnomalies) == 0 && len(gotAnomalies) == 0 {
+				wantAnomalies, gotAnomalies = nil, nil
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("record %d: rows\n%q\nwant\n%q", i+1, got, want)
+			}
+			if gotID != wantID {
+				t.Errorf("record %d: id %q, want %q", i+1, gotID, wantID)
+			}
+			if !reflect.DeepEqual(gotAnomalies, wantAnomalies) {
+				t.Errorf("record %d: anomalies %v, want %v", i+1, gotAnomalies, wantAnomalies)
+			}
+		}
+	}
+}
+
+func TestParserLenient(t *testing.T) {
+	rec := `{"leader":"L","fields":[{"001":"a"},5,{"100":[1]},` +
+		`{"245":{"ind1":1,"subfields":[{"a":"x"},7,{"b":2}]}},` +
+		`{"650":{"ind1":" ","ind2":" ","subfields":"no"}},{"651":{"ind1":" ","ind2":" "}}]}`
+	filter := NewFilter(Bib)
+	filter.RequireID = false
+	p := NewParser()
+	if _, _, _, err := p.Transform(&rec, "ACTUAL", "", filter); err == nil {
+		t.Fatal("strict parser accepted malformed record")
+	}
+	p.Lenient = true
+	mrecs, _, anomalies, err := p.Transform(&rec, "ACTUAL", "", filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRows := []Marc{
+		{Line: 1, Field: "000", Ord: 1, Content: "L"},
+		{Line: 2, Field: "001", Ord: 1, Content: "a"},
+		{Line: 3, Field: "245", Ind1: " ", Ind2: " ", Ord: 1, SF: "a", Content: "x"},
+	}
+	if !reflect.DeepEqual(mrecs, wantRows) {
+		t.Errorf("rows\n%q\nwant\n%q", mrecs, wantRows)
+	}
+	var got []string
+	for _, a := range anomalies {
+		got = append(got, a.String())
+	}
+	want := []string{
+		`"fields" element is not an object; element skipped`,
+		`field 100 (1): unknown data type in field "100"; field skipped`,
+		`field 245 (1): "ind1" wrong type; blank substituted`,
+		`field 245 (1): "subfields" element is not an object; subfield skipped`,
+		`field 245 (1): subfield value is not a string; subfield skipped`,
+		`field 245 (1): "ind2" not found; blank substituted`,
+		`field 650 (1): "subfields" is not an array; field skipped`,
+		`field 651 (1): "subfields" not found; field skipped`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("anomalies\n%q\nwant\n%q", got, want)
+	}
+	bad := `{"leader":"L","fields":{}}`
+	if _, _, _, err = p.Transform(&bad, "ACTUAL", "", filter); err == nil {
+		t.Error("lenient parser accepted record with malformed fields array")
+	}
+}
+
+// validFixtures returns the fixtures that are transformed without error.
+func validFixtures(b *testing.B) []string {
+	var recs []string
+	for _, rec := range readFixtures(b) {
+		rec := rec
+		if _, _, _, err := decoderTransform(&rec, "ACTUAL", "", NewFilter(Bib)); err == nil {
+			recs = append(recs, rec)
+		}
+	}
+	return recs
+}
+
+func BenchmarkParser(b *testing.B) {
+	recs := validFixtures(b)
+	filter := NewFilter(Bib)
+	p := NewParser()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range recs {
+			if _, _, _, err := p.Transform(&recs[j], "ACTUAL", "", filter); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	recs := validFixtures(b)
+	filter := NewFilter(Bib)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range recs {
+			if _, _, _, err := decoderTransform(&recs[j], "ACTUAL", "", filter); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package subject assembles subject headings from the 6XX fields of
+// transformed SRS MARC records.
+package subject
+
+import (
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/textnorm"
+)
+
+// Separator is placed between the components of an assembled heading.
+const Separator = "--"
+
+// Heading is a subject heading assembled from a 6XX field occurrence.
+type Heading struct {
+	Field string
+	Ord   int16
+	Ind2  string
+	// Thesaurus is the source of the heading, from the second indicator
+	// or, if the second indicator is 7 or does not identify a thesaurus,
+	// from $2.
+	Thesaurus string
+	// Heading is the components joined by Separator.
+	Heading string
+	// Components lists the main heading, followed by each subdivision
+	// ($v, $x, $y, $z), in the order of the subfields in the record.  Other
+	// subfields that follow a subdivision form a further component after
+	// it, rather than being moved into the main heading.
+	Components []string
+	// AuthorityURIs lists $0 and RWOURIs lists $1.
+	AuthorityURIs []string
+	RWOURIs       []string
+}
+
+// indicated lists the fields in which the second indicator identifies the
+// thesaurus.  In other 6XX fields, only $2 is used.
+var indicated = map[string]bool{
+	"600": true, "610": true, "611": true, "630": true, "647": true, "648": true, "650": true, "651": true,
+	"655": true,
+}
+
+// thesauri maps the second indicator of a 6XX field to its thesaurus.
+var thesauri = map[string]string{
+	"0": "lcsh",
+	"1": "lcshac",
+	"2": "mesh",
+	"3": "nal",
+	"5": "cash",
+	"6": "rvm",
+}
+
+// Extract returns the subject headings in the 6XX fields of a transformed
+// record.
+func Extract(mrecs []srs.Marc) []Heading {
+	var hs []Heading
+	for _, sfs := range srs.Fields(mrecs) {
+		m := sfs[0]
+		if !strings.HasPrefix(m.Field, "6") || m.SF == "" {
+			continue
+		}
+		h := Heading{Field: m.Field, Ord: m.Ord, Ind2: m.Ind2}
+		useSource := !indicated[m.Field] || m.Ind2 == "7"
+		if indicated[m.Field] {
+			h.Thesaurus = thesauri[m.Ind2]
+		}
+		relator := "e"
+		if m.Field == "611" {
+			relator = "j"
+		}
+		var main []string
+		for _, sf := range sfs {
+			v := strings.TrimSpace(sf.Content)
+			if v == "" {
+				continue
+			}
+			switch {
+			case sf.SF == "0":
+				h.AuthorityURIs = append(h.AuthorityURIs, v)
+			case sf.SF == "1":
+				h.RWOURIs = append(h.RWOURIs, v)
+			case sf.SF == "2":
+				if useSource && h.Thesaurus == "" {
+					h.Thesaurus = v
+				}
+			case sf.SF == "v" || sf.SF == "x" || sf.SF == "y" || sf.SF == "z":
+				if len(main) != 0 {
+					h.Components = append(h.Components, textnorm.TrimISBD(strings.Join(main, " ")))
+					main = nil
+				}
+				h.Components = append(h.Components, textnorm.TrimISBD(v))
+			case sf.SF == relator || sf.SF == "4" || (sf.SF >= "0" && sf.SF <= "9"):
+				// Relators and control subfields are not part of the
+				// heading.
+			default:
+				main = append(main, v)
+			}
+		}
+		if len(main) != 0 {
+			h.Components = append(h.Components, textnorm.TrimISBD(strings.Join(main, " ")))
+		}
+		if len(h.Components) == 0 {
+			continue
+		}
+		h.Heading = strings.Join(h.Components, Separator)
+		hs = append(hs, h)
+	}
+	return hs
+}
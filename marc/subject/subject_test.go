@@ -0,0 +1,65 @@
+package subject
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+// field returns the rows of a 6XX field occurrence with subfields given as
+// alternating codes and values.
+func field(tag string, ord int16, ind2 string, sfs ...string) []srs.Marc {
+	var rows []srs.Marc
+	for i := 0; i+1 < len(sfs); i += 2 {
+		rows = append(rows, srs.Marc{Field: tag, Ord: ord, Ind1: " ", Ind2: ind2, SF: sfs[i], Content: sfs[i+1]})
+	}
+	return rows
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name  string
+		mrecs []srs.Marc
+		want  []Heading
+	}{
+		{"topical", field("650", 1, "0", "a", "Libraries", "z", "France", "x", "History", "y", "20th century.",
+			"0", "http://id.loc.gov/authorities/subjects/sh85076502"), []Heading{{
+			Field: "650", Ord: 1, Ind2: "0", Thesaurus: "lcsh",
+			Heading:       "Libraries--France--History--20th century",
+			Components:    []string{"Libraries", "France", "History", "20th century"},
+			AuthorityURIs: []string{"http://id.loc.gov/authorities/subjects/sh85076502"},
+		}}},
+		{"personal name", field("600", 1, "0", "a", "Shakespeare, William,", "d", "1564-1616", "e", "author.",
+			"x", "Criticism and interpretation."), []Heading{{
+			Field: "600", Ord: 1, Ind2: "0", Thesaurus: "lcsh",
+			Heading:    "Shakespeare, William, 1564-1616--Criticism and interpretation",
+			Components: []string{"Shakespeare, William, 1564-1616", "Criticism and interpretation"},
+		}}},
+		{"subfield after subdivision", field("610", 1, "0", "a", "United States.", "x", "History",
+			"b", "Congress."), []Heading{{
+			Field: "610", Ord: 1, Ind2: "0", Thesaurus: "lcsh",
+			Heading:    "United States--History--Congress",
+			Components: []string{"United States", "History", "Congress"},
+		}}},
+		{"source in $2", field("650", 1, "7", "a", "Libraries.", "2", "fast", "1", "http://example.org/x"),
+			[]Heading{{
+				Field: "650", Ord: 1, Ind2: "7", Thesaurus: "fast",
+				Heading:    "Libraries",
+				Components: []string{"Libraries"},
+				RWOURIs:    []string{"http://example.org/x"},
+			}}},
+		{"meeting relator", field("611", 1, "2", "a", "Congress", "j", "host.", "v", "Congresses."), []Heading{{
+			Field: "611", Ord: 1, Ind2: "2", Thesaurus: "mesh",
+			Heading:    "Congress--Congresses",
+			Components: []string{"Congress", "Congresses"},
+		}}},
+		{"not a subject", field("700", 1, " ", "a", "Smith, John."), nil},
+		{"empty", field("650", 1, "0", "2", "lcsh"), nil},
+	}
+	for _, tt := range tests {
+		if got := Extract(tt.mrecs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Extract() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
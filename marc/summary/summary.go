@@ -0,0 +1,120 @@
+// Package summary extracts the main bibliographic elements of a transformed
+// SRS MARC record.
+package summary
+
+import (
+	"strings"
+
+	"github.com/library-data-platform/ldpmarc/marc/fixed"
+	"github.com/library-data-platform/ldpmarc/marc/identifier"
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/textnorm"
+)
+
+// Summary contains the main bibliographic elements of a record.  An element
+// that is not present is "".
+type Summary struct {
+	// Title is 245 $a$b$n$p.
+	Title string
+	// MainEntry is the first 100, 110, 111, or 130 field.
+	MainEntry string
+	// Place, Publisher, and Date are from 260 or, if not present, 264
+	// with second indicator 1 (publication).
+	Place     string
+	Publisher string
+	Date      string
+	// Extent is 300 $a.
+	Extent string
+	// Language is 008/35-37 or, if not present, the first 041 $a.
+	Language string
+	// Edition is 250 $a.
+	Edition string
+	// Series is 490 $a or, if not present, 830 $a.
+	Series string
+	// ISBN and ISSN are the first valid normalized ISBN and ISSN.
+	ISBN string
+	ISSN string
+}
+
+// Summarize extracts the main bibliographic elements of a record.
+func Summarize(mrecs []srs.Marc) Summary {
+	var s Summary
+	fields := srs.Fields(mrecs)
+	s.Title = join(first(fields, "245"), "abnp")
+	for _, f := range fields {
+		if tag := f[0].Field; tag == "100" || tag == "110" || tag == "111" || tag == "130" {
+			s.MainEntry = join(f, "abcdfgjklnpqtu")
+			break
+		}
+	}
+	pub := first(fields, "260")
+	if pub == nil {
+		for _, f := range fields {
+			if f[0].Field == "264" && f[0].Ind2 == "1" {
+				pub = f
+				break
+			}
+		}
+	}
+	s.Place = join(pub, "a")
+	s.Publisher = join(pub, "b")
+	s.Date = join(pub, "c")
+	s.Extent = join(first(fields, "300"), "a")
+	if f := first(fields, "008"); f != nil {
+		if v, ok := fixed.Value(f[0].Content, fixed.Position{Start: 35, Length: 3}, 0).(string); ok {
+			if v = strings.TrimSpace(strings.Trim(v, "|")); len(v) == 3 {
+				s.Language = v
+			}
+		}
+	}
+	if s.Language == "" {
+		s.Language = subfield(first(fields, "041"), "a")
+	}
+	s.Edition = join(first(fields, "250"), "a")
+	if s.Series = join(first(fields, "490"), "a"); s.Series == "" {
+		s.Series = join(first(fields, "830"), "a")
+	}
+	for _, id := range identifier.Extract(mrecs) {
+		switch {
+		case id.Type == identifier.ISBN && id.Valid && s.ISBN == "":
+			s.ISBN = id.Normalized
+		case id.Type == identifier.ISSN && id.Valid && s.ISSN == "":
+			s.ISSN = id.Normalized
+		}
+	}
+	return s
+}
+
+// first returns the first occurrence of a field having tag, or nil.
+func first(fields [][]srs.Marc, tag string) []srs.Marc {
+	for _, f := range fields {
+		if f[0].Field == tag {
+			return f
+		}
+	}
+	return nil
+}
+
+// join returns the subfields of f having codes listed in codes, in order,
+// separated by blanks, and trimmed of ISBD punctuation.
+func join(f []srs.Marc, codes string) string {
+	var parts []string
+	for _, sf := range f {
+		if sf.SF != "" && strings.Contains(codes, sf.SF) {
+			if v := textnorm.TrimISBD(sf.Content); v != "" {
+				parts = append(parts, v)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// subfield returns the first subfield of f having code.
+func subfield(f []srs.Marc, code string) string {
+	for _, sf := range f {
+		if sf.SF == code {
+			return strings.TrimSpace(sf.Content)
+		}
+	}
+	return ""
+}
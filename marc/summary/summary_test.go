@@ -0,0 +1,75 @@
+package summary
+
+import (
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+// sf returns a subfield row of a data field.
+func sf(field string, ord int16, ind2, code, content string) srs.Marc {
+	return srs.Marc{Field: field, Ord: ord, Ind1: " ", Ind2: ind2, SF: code, Content: content}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name  string
+		mrecs []srs.Marc
+		want  Summary
+	}{
+		{"book", []srs.Marc{
+			{Field: "008", Ord: 1, Content: "980101s1998    nyu           000 0 eng d"},
+			sf("020", 1, " ", "a", "0306406152 (pbk.)"),
+			sf("020", 2, " ", "a", "0306406153"),
+			sf("100", 1, " ", "a", "Grahame, Kenneth,"),
+			sf("100", 1, " ", "d", "1859-1932."),
+			sf("245", 1, "4", "a", "The wind in the willows /"),
+			sf("245", 1, "4", "c", "Kenneth Grahame."),
+			sf("250", 1, " ", "a", "2nd ed."),
+			sf("264", 1, "4", "c", "©1998"),
+			sf("264", 2, "1", "a", "New York :"),
+			sf("264", 2, "1", "b", "Scribner,"),
+			sf("264", 2, "1", "c", "1998."),
+			sf("300", 1, " ", "a", "259 p. ;"),
+			sf("490", 1, " ", "a", "Classics ;"),
+			sf("830", 1, "0", "a", "Scribner classics."),
+		}, Summary{
+			Title:     "The wind in the willows",
+			MainEntry: "Grahame, Kenneth 1859-1932",
+			Place:     "New York",
+			Publisher: "Scribner",
+			Date:      "1998",
+			Extent:    "259 p.",
+			Language:  "eng",
+			Edition:   "2nd ed",
+			Series:    "Classics",
+			ISBN:      "9780306406157",
+		}},
+		{"serial", []srs.Marc{
+			{Field: "008", Ord: 1, Content: "750101c19759999fr mr p       0   a0|||  "},
+			sf("022", 1, " ", "a", "0378-5955"),
+			sf("041", 1, " ", "a", "fre"),
+			sf("041", 1, " ", "a", "eng"),
+			sf("245", 1, "0", "a", "Revue :"),
+			sf("245", 1, "0", "b", "bulletin trimestriel."),
+			sf("245", 1, "0", "n", "Série 2."),
+			sf("260", 1, " ", "a", "Paris :"),
+			sf("260", 1, " ", "b", "Société,"),
+			sf("264", 1, "1", "a", "Lyon"),
+			sf("830", 1, "0", "a", "Revues savantes."),
+		}, Summary{
+			Title:     "Revue bulletin trimestriel Série 2",
+			Place:     "Paris",
+			Publisher: "Société",
+			Language:  "fre",
+			Series:    "Revues savantes",
+			ISSN:      "0378-5955",
+		}},
+		{"empty", nil, Summary{}},
+	}
+	for _, tt := range tests {
+		if got := Summarize(tt.mrecs); got != tt.want {
+			t.Errorf("%s: Summarize() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,780 @@
+package marc
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/library-data-platform/ldpmarc/marc/callnum"
+	"github.com/library-data-platform/ldpmarc/marc/contributor"
+	"github.com/library-data-platform/ldpmarc/marc/derived"
+	"github.com/library-data-platform/ldpmarc/marc/fixed"
+	"github.com/library-data-platform/ldpmarc/marc/format"
+	"github.com/library-data-platform/ldpmarc/marc/identifier"
+	"github.com/library-data-platform/ldpmarc/marc/inc"
+	"github.com/library-data-platform/ldpmarc/marc/language"
+	"github.com/library-data-platform/ldpmarc/marc/link"
+	"github.com/library-data-platform/ldpmarc/marc/mapping"
+	"github.com/library-data-platform/ldpmarc/marc/pubdate"
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/subject"
+	"github.com/library-data-platform/ldpmarc/marc/summary"
+	"github.com/library-data-platform/ldpmarc/marc/util"
+	"github.com/library-data-platform/ldpmarc/marc/validate"
+)
+
+// derivedTables returns the derived tables enabled by opts.  Any report files
+// are created and will be closed by calling the returned function.
+func derivedTables(opts *TransformOptions) ([]*derived.Table, func(), error) {
+	var files []*os.File
+	closeFiles := func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+	opts.mapping = nil
+	opts.formats = nil
+	opts.hosts = nil
+	opts.validateSeverity = nil
+	tables := make([]*derived.Table, 0)
+	if opts.FieldLinks {
+		tables = append(tables, fieldLinkTable(opts))
+	}
+	if opts.Lenient {
+		tables = append(tables, diagnosticTable(opts))
+	}
+	// The remaining tables are generated only from bibliographic records.
+	if opts.mode == util.BibMode {
+		if opts.Leader008 {
+			tables = append(tables, leader008Table(opts))
+		}
+		if opts.AltGraphic {
+			tables = append(tables, altGraphicTable(opts))
+		}
+		if opts.Identifiers {
+			tables = append(tables, identifierTable(opts))
+		}
+		if opts.CallNumbers {
+			tables = append(tables, callNumberTable(opts))
+		}
+		if opts.Subjects {
+			tables = append(tables, subjectTable(opts))
+		}
+		if opts.Dates {
+			tables = append(tables, dateTable(opts))
+		}
+		if opts.Languages {
+			tables = append(tables, languageTable(opts))
+		}
+		if opts.Formats || opts.FormatRules != "" {
+			opts.formats = format.Default
+			if opts.FormatRules != "" {
+				rs, err := format.Load(opts.FormatRules)
+				if err != nil {
+					closeFiles()
+					return nil, nil, fmt.Errorf("reading format rules: %v", err)
+				}
+				opts.formats = rs
+			}
+			tables = append(tables, formatTable(opts, opts.formats))
+		}
+		if opts.Links || opts.LinkHosts != "" {
+			if opts.LinkHosts != "" {
+				h, err := link.LoadHosts(opts.LinkHosts)
+				if err != nil {
+					closeFiles()
+					return nil, nil, fmt.Errorf("reading link hosts: %v", err)
+				}
+				opts.hosts = h
+			}
+			tables = append(tables, linkTable(opts, opts.hosts))
+		}
+		if opts.Summary {
+			tables = append(tables, summaryTable(opts))
+		}
+		if opts.Names {
+			tables = append(tables, nameTable(opts))
+		}
+		if opts.Decode007 {
+			tables = append(tables, physicalTable(opts))
+		}
+		if opts.Mapping != "" {
+			m, err := mapping.Load(opts.Mapping)
+			if err != nil {
+				closeFiles()
+				return nil, nil, fmt.Errorf("reading mapping: %v", err)
+			}
+			if reservedTables[m.Table] || derivedName(opts, m.Table) == opts.Loc.TablefinalTable {
+				closeFiles()
+				return nil, nil, fmt.Errorf("reading mapping: table name is reserved: %s", m.Table)
+			}
+			opts.mapping = m
+			t := mappingTable(opts, m)
+			if opts.MappingCSV != "" {
+				f, err := os.Create(opts.MappingCSV)
+				if err != nil {
+					closeFiles()
+					return nil, nil, err
+				}
+				files = append(files, f)
+				t.Report = csv.NewWriter(f)
+				if err = t.Report.Write(t.ColumnNames()); err != nil {
+					closeFiles()
+					return nil, nil, err
+				}
+			}
+			tables = append(tables, t)
+		}
+		if opts.Validate || opts.ValidateReport != "" {
+			t, err := validationTable(opts)
+			if err != nil {
+				closeFiles()
+				return nil, nil, err
+			}
+			if opts.ValidateReport != "" {
+				f, err := os.Create(opts.ValidateReport)
+				if err != nil {
+					closeFiles()
+					return nil, nil, err
+				}
+				files = append(files, f)
+				t.Report = csv.NewWriter(f)
+			}
+			tables = append(tables, t)
+		}
+	}
+	names := make(map[string]bool)
+	for _, t := range tables {
+		if t.Schema == "" {
+			t.Schema = opts.Loc.TablefinalSchema
+		}
+		t.IDColumn = opts.mode.IDColumn
+		if names[t.Final()] {
+			closeFiles()
+			return nil, nil, fmt.Errorf("duplicate derived table: %s", t.Final())
+		}
+		names[t.Final()] = true
+	}
+	return tables, closeFiles, nil
+}
+
+// dbTables returns the derived tables that are written to the database.
+func dbTables(tables []*derived.Table) []*derived.Table {
+	dt := make([]*derived.Table, 0)
+	for _, t := range tables {
+		if t.Report == nil {
+			dt = append(dt, t)
+		}
+	}
+	return dt
+}
+
+// writtenTables returns the derived tables written by a full update, which
+// include database tables only if output is not written to a CSV file.
+func (o *TransformOptions) writtenTables() []*derived.Table {
+	if o.CSVFileName == "" {
+		return o.derived
+	}
+	rt := make([]*derived.Table, 0)
+	for _, t := range o.derived {
+		if t.Report != nil {
+			rt = append(rt, t)
+		}
+	}
+	return rt
+}
+
+// derivedAvail reports whether the derived tables can be updated
+// incrementally.  This requires that all derived tables written to the
+// database exist, and that none is written to a report file, which is
+// complete only after a full update.
+func derivedAvail(conn *pgx.Conn, tables []*derived.Table) (bool, error) {
+	if len(dbTables(tables)) != len(tables) {
+		return false, nil
+	}
+	for _, t := range tables {
+		ok, err := derived.Exists(context.TODO(), conn, t)
+		if err != nil {
+			return false, fmt.Errorf("checking for derived table: %s: %v", t.Final(), err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// dropStale drops the derived tables written by the previous full update
+// that are no longer enabled, which would otherwise remain out of date.  It
+// must be called before the new config is stored.
+func dropStale(opts *TransformOptions, conn *pgx.Conn) error {
+	prev, err := inc.Config(conn, opts.mode)
+	if err != nil {
+		return fmt.Errorf("reading previous config: %v", err)
+	}
+	enabled := make(map[string]bool)
+	for _, t := range dbTables(opts.derived) {
+		enabled[t.Final()] = true
+	}
+	for _, name := range configTables(prev) {
+		if enabled[name] {
+			continue
+		}
+		if _, err = conn.Exec(context.TODO(), "DROP TABLE IF EXISTS "+name); err != nil {
+			return fmt.Errorf("dropping derived table: %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// configTables returns the derived tables listed in a config string.
+func configTables(config string) []string {
+	i := strings.LastIndex(config, ";tables=")
+	if i == -1 {
+		return nil
+	}
+	list := config[i+len(";tables="):]
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+// describeRecords describes the records selected by the filter, for use in
+// comments on derived tables.
+func describeRecords(opts *TransformOptions) string {
+	if !opts.filter.NonActual() {
+		return "current SRS MARC records"
+	}
+	return "SRS MARC records having state " + strings.Join(opts.filter.States, ", ")
+}
+
+// reservedTables is the set of names of the built-in derived tables, which a
+// mapping table may not use whether or not they are enabled.
+var reservedTables = map[string]bool{
+	"007":          true,
+	"880":          true,
+	"call_numbers": true,
+	"dates":        true,
+	"diagnostics":  true,
+	"field_link":   true,
+	"format":       true,
+	"identifiers":  true,
+	"languages":    true,
+	"leader_008":   true,
+	"links":        true,
+	"names":        true,
+	"subjects":     true,
+	"summary":      true,
+}
+
+// derivedName returns the final name of a derived table for the current record
+// type, in the naming style of the database.
+func derivedName(opts *TransformOptions, name string) string {
+	if opts.Metadb {
+		return "marc" + opts.mode.Suffix + "__" + name
+	}
+	return "srs_marc" + opts.mode.Suffix + "_" + name
+}
+
+func leader008Table(opts *TransformOptions) *derived.Table {
+	var positions []fixed.Position
+	positions = append(positions, fixed.Leader...)
+	positions = append(positions, fixed.Position{Name: "material"})
+	positions = append(positions, fixed.Common008...)
+	seen := make(map[string]bool)
+	for _, m := range fixed.Materials {
+		for _, p := range fixed.Material008[m] {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				positions = append(positions, p)
+			}
+		}
+	}
+	columns := make([]derived.Column, 0, len(positions))
+	for _, p := range positions {
+		var t string
+		switch p.Kind {
+		case fixed.Int:
+			t = "integer"
+		case fixed.Date:
+			t = "date"
+		default:
+			t = "text"
+		}
+		columns = append(columns, derived.Column{Name: p.Name, Type: t})
+	}
+	return &derived.Table{
+		Name:    derivedName(opts, "leader_008"),
+		Comment: "Leader and 008 elements of " + describeRecords(opts),
+		Columns: columns,
+		Index:   []string{"type_of_record", "bibliographic_level", "material", "date1", "language"},
+		Rows: func(r *derived.Record) [][]any {
+			var leader, f008 string
+			var ok bool
+			for _, m := range r.Marc {
+				switch m.Field {
+				case "000":
+					leader, ok = m.Content, true
+				case "008":
+					if f008 == "" {
+						f008 = m.Content
+					}
+				}
+			}
+			if !ok {
+				return nil
+			}
+			material := fixed.Material(leader)
+			values := make(map[string]any)
+			for _, p := range fixed.Leader {
+				values[p.Name] = fixed.Value(leader, p, 0)
+			}
+			if material != "" {
+				values["material"] = material
+			}
+			if f008 != "" {
+				for _, p := range fixed.Common008 {
+					values[p.Name] = fixed.Value(f008, p, 0)
+				}
+				for _, p := range fixed.Material008[material] {
+					values[p.Name] = fixed.Value(f008, p, 0)
+				}
+			}
+			row := make([]any, len(positions))
+			for i, p := range positions {
+				row[i] = values[p.Name]
+			}
+			return [][]any{row}
+		},
+	}
+}
+
+func formatTable(opts *TransformOptions, rs *format.Rules) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "format"),
+		Comment: "Format classification of " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "format", Type: "text NOT NULL"},
+			{Name: "rule", Type: "integer"},
+		},
+		Index: []string{"format"},
+		Rows: func(r *derived.Record) [][]any {
+			f, line := rs.Classify(r.Marc)
+			var rule any
+			if line != 0 {
+				rule = int32(line)
+			}
+			return [][]any{{f, rule}}
+		},
+	}
+}
+
+func dateTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "dates"),
+		Comment: "Normalized publication dates of " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "date_type", Type: "text"},
+			{Name: "year_start", Type: "integer"},
+			{Name: "year_end", Type: "integer"},
+			{Name: "confidence", Type: "varchar(6)"},
+			{Name: "source", Type: "varchar(3)"},
+		},
+		Index: []string{"year_start", "year_end"},
+		Rows: func(r *derived.Record) [][]any {
+			d := pubdate.Extract(r.Marc)
+			return [][]any{{nullString(d.DateType), nullInt(d.YearStart), nullInt(d.YearEnd),
+				nullString(d.Confidence), nullString(d.Source)}}
+		},
+	}
+}
+
+func diagnosticTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "diagnostics"),
+		Comment: "Anomalies and repairs in " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "field", Type: "text"},
+			{Name: "ord", Type: "smallint"},
+			{Name: "message", Type: "text NOT NULL"},
+			{Name: "repair", Type: "text"},
+		},
+		Index: []string{"field"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, a := range r.Anomalies {
+				var ord any
+				if a.Ord != 0 {
+					ord = a.Ord
+				}
+				rows = append(rows, []any{nullString(a.Field), ord, a.Message, nullString(a.Repair)})
+			}
+			return rows
+		},
+	}
+}
+
+func languageTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "languages"),
+		Comment: "Language codes in 008 and 041 of " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "field", Type: "text NOT NULL"},
+			{Name: "ord", Type: "smallint NOT NULL"},
+			{Name: "sf", Type: "text"},
+			{Name: "code", Type: "text NOT NULL"},
+			{Name: "source", Type: "text"},
+			{Name: "name", Type: "text"},
+			{Name: "valid", Type: "boolean NOT NULL"},
+			{Name: "obsolete", Type: "boolean NOT NULL"},
+		},
+		Index: []string{"code"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, l := range language.Extract(r.Marc) {
+				rows = append(rows, []any{l.Field, l.Ord, nullString(l.SF), l.Code, nullString(l.Source),
+					nullString(l.Name), l.Valid, l.Obsolete})
+			}
+			return rows
+		},
+	}
+}
+
+func summaryTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "summary"),
+		Comment: "Main bibliographic elements of " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "title", Type: "text"},
+			{Name: "main_entry", Type: "text"},
+			{Name: "place", Type: "text"},
+			{Name: "publisher", Type: "text"},
+			{Name: "date", Type: "text"},
+			{Name: "extent", Type: "text"},
+			{Name: "language", Type: "text"},
+			{Name: "edition", Type: "text"},
+			{Name: "series", Type: "text"},
+			{Name: "isbn", Type: "text"},
+			{Name: "issn", Type: "text"},
+		},
+		Index: []string{"isbn", "issn"},
+		Rows: func(r *derived.Record) [][]any {
+			s := summary.Summarize(r.Marc)
+			return [][]any{{nullString(s.Title), nullString(s.MainEntry), nullString(s.Place),
+				nullString(s.Publisher), nullString(s.Date), nullString(s.Extent), nullString(s.Language),
+				nullString(s.Edition), nullString(s.Series), nullString(s.ISBN), nullString(s.ISSN)}}
+		},
+	}
+}
+
+func mappingTable(opts *TransformOptions, m *mapping.Mapping) *derived.Table {
+	columns := make([]derived.Column, 0, len(m.Columns))
+	for _, c := range m.Columns {
+		t := "text"
+		if c.Array {
+			t = "text[]"
+		}
+		columns = append(columns, derived.Column{Name: c.Name, Type: t})
+	}
+	return &derived.Table{
+		Name:    derivedName(opts, m.Table),
+		Comment: "Columns of " + describeRecords(opts) + " defined by a mapping configuration",
+		Columns: columns,
+		Rows: func(r *derived.Record) [][]any {
+			return [][]any{m.Row(r.Marc)}
+		},
+	}
+}
+
+func physicalTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "007"),
+		Comment: "Decoded 006 and 007 elements of " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "field", Type: "text NOT NULL"},
+			{Name: "ord", Type: "smallint NOT NULL"},
+			{Name: "category", Type: "text"},
+			{Name: "position", Type: "smallint NOT NULL"},
+			{Name: "name", Type: "text NOT NULL"},
+			{Name: "code", Type: "text NOT NULL"},
+			{Name: "value", Type: "text"},
+		},
+		Index: []string{"category", "name", "code"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, m := range r.Marc {
+				var category string
+				var elements []fixed.Element
+				switch m.Field {
+				case "006":
+					category, elements = fixed.Decode006(m.Content)
+				case "007":
+					category, elements = fixed.Decode007(m.Content)
+				default:
+					continue
+				}
+				for _, e := range elements {
+					rows = append(rows, []any{m.Field, m.Ord, nullString(category), int16(e.Position), e.Name,
+						e.Code, nullString(e.Value)})
+				}
+			}
+			return rows
+		},
+	}
+}
+
+func altGraphicTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "880"),
+		Comment: "Linkage of 880 fields in " + describeRecords(opts) + " to the fields they parallel",
+		Columns: []derived.Column{
+			{Name: "ord", Type: "smallint NOT NULL"},
+			{Name: "linked_field", Type: "text NOT NULL"},
+			{Name: "linked_ord", Type: "smallint"},
+			{Name: "occurrence", Type: "text NOT NULL"},
+			{Name: "script", Type: "text"},
+			{Name: "orientation", Type: "text"},
+		},
+		Index: []string{"linked_field", "script"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, l := range srs.AltGraphicLinks(r.Marc) {
+				var linkedOrd any
+				if l.LinkedOrd != 0 {
+					linkedOrd = l.LinkedOrd
+				}
+				rows = append(rows, []any{l.Ord, l.Tag, linkedOrd, l.Occurrence, nullString(l.Script),
+					nullString(l.Orientation)})
+			}
+			return rows
+		},
+	}
+}
+
+func identifierTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "identifiers"),
+		Comment: "Normalized standard identifiers in " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "type", Type: "varchar(4) NOT NULL"},
+			{Name: "raw", Type: "text NOT NULL"},
+			{Name: "normalized", Type: "text"},
+			{Name: "valid", Type: "boolean NOT NULL"},
+		},
+		Index: []string{"normalized"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, id := range identifier.Extract(r.Marc) {
+				rows = append(rows, []any{id.Type, id.Raw, nullString(id.Normalized), id.Valid})
+			}
+			return rows
+		},
+	}
+}
+
+func callNumberTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "call_numbers"),
+		Comment: "Parsed LC and Dewey call numbers in " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "field", Type: "text NOT NULL"},
+			{Name: "ord", Type: "smallint NOT NULL"},
+			{Name: "scheme", Type: "varchar(5) NOT NULL"},
+			{Name: "call_number", Type: "text NOT NULL"},
+			{Name: "class", Type: "text"},
+			{Name: "class_number", Type: "text"},
+			{Name: "cutters", Type: "text"},
+			{Name: "year", Type: "text"},
+			{Name: "remainder", Type: "text"},
+			{Name: "sort_key", Type: "text COLLATE \"C\""},
+			{Name: "unparsable", Type: "boolean NOT NULL"},
+		},
+		Index: []string{"scheme", "sort_key"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, cn := range callnum.Extract(r.Marc) {
+				rows = append(rows, []any{cn.Field, cn.Ord, cn.Scheme, cn.Raw, nullString(cn.Class),
+					nullString(cn.Number), nullString(strings.Join(cn.Cutters, " ")), nullString(cn.Year),
+					nullString(cn.Rest), nullString(cn.SortKey), !cn.Parsed})
+			}
+			return rows
+		},
+	}
+}
+
+func subjectTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "subjects"),
+		Comment: "Subject headings assembled from 6XX fields of " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "field", Type: "text NOT NULL"},
+			{Name: "ord", Type: "smallint NOT NULL"},
+			{Name: "ind2", Type: "varchar(1) NOT NULL"},
+			{Name: "thesaurus", Type: "text"},
+			{Name: "heading", Type: "text NOT NULL"},
+			{Name: "authority_uris", Type: "text[]"},
+			{Name: "rwo_uris", Type: "text[]"},
+			{Name: "components", Type: "text[] NOT NULL"},
+		},
+		Index: []string{"field", "thesaurus"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, h := range subject.Extract(r.Marc) {
+				rows = append(rows, []any{h.Field, h.Ord, h.Ind2, nullString(h.Thesaurus), h.Heading,
+					nullStrings(h.AuthorityURIs), nullStrings(h.RWOURIs), h.Components})
+			}
+			return rows
+		},
+	}
+}
+
+func linkTable(opts *TransformOptions, hosts *link.Hosts) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "links"),
+		Comment: "Electronic access links (856) in " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "ord", Type: "smallint NOT NULL"},
+			{Name: "ind2", Type: "varchar(1) NOT NULL"},
+			{Name: "relationship", Type: "text"},
+			{Name: "url", Type: "text NOT NULL"},
+			{Name: "link_text", Type: "text"},
+			{Name: "public_note", Type: "text"},
+			{Name: "materials", Type: "text"},
+			{Name: "host", Type: "text"},
+			{Name: "valid", Type: "boolean NOT NULL"},
+			{Name: "host_status", Type: "varchar(8)"},
+		},
+		Index: []string{"host", "host_status"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, l := range link.Extract(r.Marc) {
+				var status any
+				if hosts != nil {
+					status = hosts.Check(&l)
+				}
+				rows = append(rows, []any{l.Ord, l.Ind2, nullString(l.Relationship), l.URL, nullString(l.LinkText),
+					nullString(l.PublicNote), nullString(l.Materials), nullString(l.Host), l.Valid, status})
+			}
+			return rows
+		},
+	}
+}
+
+func nameTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "names"),
+		Comment: "Names in 1XX and 7XX fields of " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "field", Type: "text NOT NULL"},
+			{Name: "ord", Type: "smallint NOT NULL"},
+			{Name: "entry", Type: "varchar(5) NOT NULL"},
+			{Name: "name_type", Type: "varchar(9) NOT NULL"},
+			{Name: "name", Type: "text NOT NULL"},
+			{Name: "dates", Type: "text"},
+			{Name: "roles", Type: "text[]"},
+			{Name: "relator_codes", Type: "text[]"},
+			{Name: "authority_uris", Type: "text[]"},
+		},
+		Index: []string{"name_type", "name"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, c := range contributor.Extract(r.Marc) {
+				entry := "added"
+				if c.Main {
+					entry = "main"
+				}
+				rows = append(rows, []any{c.Field, c.Ord, entry, c.Type, c.Name, nullString(c.Dates),
+					nullStrings(c.Roles), nullStrings(c.RelatorCodes), nullStrings(c.AuthorityURIs)})
+			}
+			return rows
+		},
+	}
+}
+
+func fieldLinkTable(opts *TransformOptions) *derived.Table {
+	return &derived.Table{
+		Name:    derivedName(opts, "field_link"),
+		Comment: "Field linking and sequence numbers ($8) in " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "field", Type: "text NOT NULL"},
+			{Name: "ord", Type: "smallint NOT NULL"},
+			{Name: "link_number", Type: "integer NOT NULL"},
+			{Name: "sequence_number", Type: "integer"},
+			{Name: "link_type", Type: "text"},
+		},
+		Index: []string{"field", "link_number"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, m := range r.Marc {
+				if m.SF != "8" {
+					continue
+				}
+				l, ok := srs.ParseFieldLink(m.Content)
+				if !ok {
+					continue
+				}
+				var seq any
+				if l.Sequence >= 0 {
+					seq = l.Sequence
+				}
+				rows = append(rows, []any{m.Field, m.Ord, l.Link, seq, nullString(l.Type)})
+			}
+			return rows
+		},
+	}
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullInt(i int) any {
+	if i == 0 {
+		return nil
+	}
+	return int32(i)
+}
+
+func nullStrings(s []string) any {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+func validationTable(opts *TransformOptions) (*derived.Table, error) {
+	sev, err := validate.ParseSeverities(opts.ValidateSeverity)
+	if err != nil {
+		return nil, err
+	}
+	opts.validateSeverity = sev
+	return &derived.Table{
+		Schema:  tableoutSchema,
+		Name:    "validation",
+		Comment: "MARC 21 validation findings for " + describeRecords(opts),
+		Columns: []derived.Column{
+			{Name: "field", Type: "text NOT NULL"},
+			{Name: "ord", Type: "smallint NOT NULL"},
+			{Name: "sf", Type: "text NOT NULL"},
+			{Name: "severity", Type: "varchar(7) NOT NULL"},
+			{Name: "message", Type: "text NOT NULL"},
+		},
+		Index: []string{"field", "severity"},
+		Rows: func(r *derived.Record) [][]any {
+			var rows [][]any
+			for _, f := range validate.Validate(r.Leader, r.Marc) {
+				if sev[f.Severity] {
+					rows = append(rows, []any{f.Field, f.Ord, f.SF, string(f.Severity), f.Message})
+				}
+			}
+			return rows
+		},
+	}, nil
+}
@@ -0,0 +1,41 @@
+package marc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/util"
+)
+
+func TestMappingTableName(t *testing.T) {
+	tests := []struct {
+		table  string
+		metadb bool
+		ok     bool
+	}{
+		{"wide", true, true},
+		{"wide", false, true},
+		{"t", true, false},
+		{"t", false, true},
+		{"summary", true, false},
+		{"field_link", false, false},
+	}
+	for _, tt := range tests {
+		filename := filepath.Join(t.TempDir(), "mapping.yaml")
+		if err := os.WriteFile(filename, []byte("table: "+tt.table+"\ncolumns:\n  title: 245$a\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		opts := &TransformOptions{Metadb: tt.metadb, Mapping: filename}
+		opts.mode = util.BibMode
+		opts.Loc = setupLocations(opts, util.BibMode)
+		opts.filter = setupFilter(opts, util.BibMode)
+		_, closeReports, err := derivedTables(opts)
+		if (err == nil) != tt.ok {
+			t.Errorf("table %q (Metadb %v): derivedTables() error = %v, want ok %v", tt.table, tt.metadb, err, tt.ok)
+		}
+		if err == nil {
+			closeReports()
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package textnorm normalizes the Unicode form of MARC content and folds it
+// into a form suitable for matching.
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NFC returns s in Unicode Normalization Form C.
+func NFC(s string) string {
+	return norm.NFC.String(s)
+}
+
+// TrimISBD removes blanks and trailing ISBD punctuation (" /", " :", " ;",
+// " =", ",", and a final period) from s.  A final period is retained if it
+// follows an initial, as in "Smith, John A." or "Tolkien, J.R.R.", or ends an
+// ellipsis or "etc.".
+func TrimISBD(s string) string {
+	s = strings.TrimSpace(s)
+	for {
+		t := strings.TrimRight(s, " /:;=,")
+		if strings.HasSuffix(t, ".") && !strings.HasSuffix(t, "...") && !strings.HasSuffix(t, "etc.") &&
+			!isInitial(t) {
+			t = strings.TrimSuffix(t, ".")
+		}
+		if t == s {
+			return s
+		}
+		s = t
+	}
+}
+
+// isInitial reports whether s ends with a single letter followed by a
+// period.
+func isInitial(s string) bool {
+	r := []rune(strings.TrimSuffix(s, "."))
+	n := len(r)
+	if n == 0 || !unicode.IsLetter(r[n-1]) {
+		return false
+	}
+	return n == 1 || r[n-2] == ' ' || r[n-2] == '.'
+}
+
+// letters maps letters that do not decompose into a base letter and a
+// combining mark.
+var letters = map[rune]string{
+	'æ': "ae",
+	'đ': "d",
+	'ð': "d",
+	'ı': "i",
+	'ł': "l",
+	'ø': "o",
+	'œ': "oe",
+	'ß': "ss",
+	'þ': "th",
+}
+
+// Fold returns s lowercased, with diacritics removed, and with punctuation
+// and white space collapsed to single blanks.  Diacritics are removed only
+// from Latin, Greek, and Cyrillic letters; in other scripts, combining marks
+// such as the Devanagari virama or Japanese dakuten are part of a word and are
+// kept.
+func Fold(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	blank := true
+	// strip reports whether a nonspacing mark is to be removed, which
+	// depends on the letter it follows.
+	strip := true
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			if !strip {
+				b.WriteRune(r)
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mc, unicode.Me):
+			if !unicode.In(r, unicode.Mc, unicode.Me) {
+				strip = !unicode.IsLetter(r) || unicode.In(r, unicode.Latin, unicode.Greek, unicode.Cyrillic)
+			}
+			r = unicode.ToLower(r)
+			if l, ok := letters[r]; ok {
+				b.WriteString(l)
+			} else {
+				b.WriteRune(r)
+			}
+			blank = false
+		default:
+			strip = true
+			if !blank {
+				b.WriteByte(' ')
+				blank = true
+			}
+		}
+	}
+	return norm.NFC.String(strings.TrimRight(b.String(), " "))
+}
@@ -0,0 +1,50 @@
+package textnorm
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Dvořák, Antonín,", "dvorak antonin"},
+		{"Symfonie č. 9", "symfonie c 9"},
+		{"  Æsop's  fables ", "aesop s fables"},
+		{"Straße", "strasse"},
+		{"Ἀθῆναι", "αθηναι"},
+		{"Пётр Ильич", "петр ильич"},
+		{"हिन्दी", "हिन्दी"},
+		{"தமிழ்", "தமிழ்"},
+		{"ガイドブック", "ガイドブック"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTrimISBD(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"The wind in the willows /", "The wind in the willows"},
+		{"London :", "London"},
+		{"Methuen,", "Methuen"},
+		{"1908.", "1908"},
+		{"Smith, John A.", "Smith, John A."},
+		{"Tolkien, J.R.R.", "Tolkien, J.R.R."},
+		{"Bach, Johann Sebastian,", "Bach, Johann Sebastian"},
+		{"Poems, letters, etc.", "Poems, letters, etc."},
+		{"And then...", "And then..."},
+		{"Libraries ; ", "Libraries"},
+		{"vol. 2.", "vol. 2"},
+		{"A.", "A."},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := TrimISBD(tt.in); got != tt.want {
+			t.Errorf("TrimISBD(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package util
+
+import "github.com/library-data-platform/ldpmarc/marc/srs"
+
+// Mode defines how SRS records of one record type are transformed and where
+// the output is written.
+type Mode struct {
+	// Name is used in messages.
+	Name string
+	// RecordType is the SRS record type to be transformed.
+	RecordType string
+	// Suffix is appended to the names of the output and system tables;
+	// it is empty for bibliographic records.
+	Suffix string
+	// PartitionPrefix is the prefix of the output table partitions.
+	PartitionPrefix string
+	// IDColumn and HRIDColumn are the names of the output columns
+	// containing the identifier from 999$i and the HRID.
+	IDColumn   string
+	HRIDColumn string
+}
+
+// BibMode transforms bibliographic records.
+var BibMode = &Mode{
+	Name:            "bibliographic",
+	RecordType:      srs.Bib,
+	PartitionPrefix: "mt",
+	IDColumn:        "instance_id",
+	HRIDColumn:      "instance_hrid",
+}
+
+// HoldingsMode transforms holdings records.
+var HoldingsMode = &Mode{
+	Name:            "holdings",
+	RecordType:      srs.Holdings,
+	Suffix:          "_holdings",
+	PartitionPrefix: "mth",
+	IDColumn:        "holdings_id",
+	HRIDColumn:      "holdings_hrid",
+}
+
+// AuthorityMode transforms authority records.
+var AuthorityMode = &Mode{
+	Name:            "authority",
+	RecordType:      srs.Authority,
+	Suffix:          "_authority",
+	PartitionPrefix: "mta",
+	IDColumn:        "authority_id",
+	HRIDColumn:      "authority_hrid",
+}
+
+// CksumTable returns the name of the checksum table.
+func (m *Mode) CksumTable() string {
+	return "marctab.cksum" + m.Suffix
+}
+
+// FieldCksumTable returns the name of the table containing checksums of
+// individual fields.
+func (m *Mode) FieldCksumTable() string {
+	return "marctab.field_cksum" + m.Suffix
+}
+
+// MetadataTable returns the name of the metadata table.
+func (m *Mode) MetadataTable() string {
+	return "marctab.metadata" + m.Suffix
+}
@@ -8,29 +8,54 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/library-data-platform/ldpmarc/marc/srs"
+	"github.com/library-data-platform/ldpmarc/marc/textnorm"
 )
 
+// Content configures processing of the content column.
+type Content struct {
+	// NFC enables normalization of content to Unicode NFC.
+	NFC bool
+	// Folded enables the content_folded column.
+	Folded bool
+}
+
+// Normalize normalizes the content of mrecs in place, if enabled.
+func (c *Content) Normalize(mrecs []srs.Marc) {
+	if !c.NFC {
+		return
+	}
+	for i := range mrecs {
+		mrecs[i].Content = textnorm.NFC(mrecs[i].Content)
+	}
+}
+
+// String returns a description of the configuration which can be compared
+// with a previous one.
+func (c *Content) String() string {
+	return fmt.Sprintf("nfc=%t;folded=%t", c.NFC, c.Folded)
+}
+
 func MD5(srsMarcAttr string) string {
 	//return "md5(r::text || m::text)"
 	return "md5(coalesce(r.external_hrid::text, '') || coalesce(r.matched_id::text, '') || coalesce(r.state::text, '') || coalesce(m." + srsMarcAttr + "::text, ''))"
 }
 
-func Transform(id, matchedID, instanceHRID, state, data *string, printerr func(string, ...interface{}), verbose int) (*string, *string, *string, string, []srs.Marc, bool) {
+func Transform(id, matchedID, instanceHRID, state, recordType, data *string, parser *srs.Parser, filter *srs.Filter, content *Content, printerr func(string, ...interface{}), verbose int) (*string, *string, *string, string, []srs.Marc, []srs.Anomaly, bool) {
 	if id == nil {
 		printerr(skipValue(id, data))
-		return nil, nil, nil, "", nil, true
+		return nil, nil, nil, "", nil, nil, true
 	}
 	if strings.TrimSpace(*id) == "" {
 		printerr(skipValue(id, data))
-		return nil, nil, nil, "", nil, true
+		return nil, nil, nil, "", nil, nil, true
 	}
 	if data == nil {
 		printerr(skipValue(id, data))
-		return nil, nil, nil, "", nil, true
+		return nil, nil, nil, "", nil, nil, true
 	}
 	if strings.TrimSpace(*data) == "" {
 		printerr(skipValue(id, data))
-		return nil, nil, nil, "", nil, true
+		return nil, nil, nil, "", nil, nil, true
 	}
 	if matchedID == nil {
 		s := ""
@@ -44,17 +69,28 @@ func Transform(id, matchedID, instanceHRID, state, data *string, printerr func(s
 		s := ""
 		state = &s
 	}
+	if recordType == nil {
+		s := ""
+		recordType = &s
+	}
 	var mrecs []srs.Marc
 	var instanceID string
+	var anomalies []srs.Anomaly
 	var err error
-	if mrecs, instanceID, err = srs.Transform(data, *state); err != nil {
+	if mrecs, instanceID, anomalies, err = parser.Transform(data, *state, *recordType, filter); err != nil {
 		printerr(skipError(id, err))
-		return nil, nil, nil, "", nil, true
+		return nil, nil, nil, "", nil, nil, true
+	}
+	if verbose >= 2 {
+		for _, a := range anomalies {
+			printerr("anomaly in record: id=%s: %s", *id, a)
+		}
 	}
+	content.Normalize(mrecs)
 	if verbose >= 2 && len(mrecs) != 0 {
 		printerr("updating: id=%s", *id)
 	}
-	return id, matchedID, instanceHRID, instanceID, mrecs, false
+	return id, matchedID, instanceHRID, instanceID, mrecs, anomalies, false
 }
 
 func skipValue(id, data *string) string {
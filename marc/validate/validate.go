@@ -0,0 +1,201 @@
+// Package validate checks transformed SRS MARC records against MARC 21
+// bibliographic tag, indicator, and subfield rules.
+package validate
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+// Severity is the severity of a Finding.
+type Severity string
+
+const (
+	// Error indicates structurally invalid data.
+	Error Severity = "error"
+	// Warning indicates a value not defined for the tag.
+	Warning Severity = "warning"
+	// Info indicates an unusual but possibly intended value.
+	Info Severity = "info"
+)
+
+// ParseSeverities parses a comma-separated list of severities.
+func ParseSeverities(list string) (map[Severity]bool, error) {
+	sev := make(map[Severity]bool)
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		switch Severity(s) {
+		case Error, Warning, Info:
+			sev[Severity(s)] = true
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown validation severity: %s", s)
+		}
+	}
+	return sev, nil
+}
+
+// SeveritySignature returns a string identifying a set of severities, in a
+// fixed order, for detecting configuration changes.
+func SeveritySignature(sev map[Severity]bool) string {
+	var list []string
+	for _, s := range []Severity{Error, Warning, Info} {
+		if sev[s] {
+			list = append(list, string(s))
+		}
+	}
+	return strings.Join(list, ",")
+}
+
+// Finding describes a problem found in a record.  Field and Ord identify the
+// field occurrence, and SF the subfield if applicable.  Ord is 0 for findings
+// about a field that is missing.
+type Finding struct {
+	Field    string
+	Ord      int16
+	SF       string
+	Severity Severity
+	Message  string
+}
+
+type rule struct {
+	repeatable bool
+	ind1       string
+	ind2       string
+	subfields  string
+}
+
+//go:embed rules.txt
+var rulesText string
+
+var rules = parseRules(rulesText)
+
+func parseRules(text string) map[string]rule {
+	m := make(map[string]rule)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) != 5 {
+			panic("invalid validation rule: " + line)
+		}
+		m[f[0]] = rule{
+			repeatable: f[1] == "R",
+			ind1:       strings.ReplaceAll(f[2], "#", " "),
+			ind2:       strings.ReplaceAll(f[3], "#", " "),
+			subfields:  f[4],
+		}
+	}
+	return m
+}
+
+// Validate checks the leader and rows of a transformed record and returns any
+// findings.  The leader is passed separately because the 000 row is present
+// only in records that have a 001 field.
+func Validate(leader string, mrecs []srs.Marc) []Finding {
+	var findings []Finding
+	add := func(field string, ord int16, sf string, sev Severity, format string, v ...any) {
+		findings = append(findings, Finding{
+			Field:    field,
+			Ord:      ord,
+			SF:       sf,
+			Severity: sev,
+			Message:  fmt.Sprintf(format, v...),
+		})
+	}
+	if leader == "" {
+		add("000", 0, "", Error, "missing leader")
+	} else if n := utf8.RuneCountInString(leader); n != 24 {
+		add("000", 1, "", Error, "leader length is %d, expected 24", n)
+	}
+	var has008, has245 bool
+	counts := make(map[string]int)
+	for i, m := range mrecs {
+		// Only the first row of a field occurrence is used for checks
+		// on the field as a whole.
+		first := i == 0 || mrecs[i-1].Field != m.Field || mrecs[i-1].Ord != m.Ord
+		if m.Field == "000" {
+			continue
+		}
+		if !isNumeric(m.Field) {
+			if first {
+				add(m.Field, m.Ord, "", Error, "non-numeric tag %q", m.Field)
+			}
+			continue
+		}
+		switch m.Field {
+		case "008":
+			has008 = true
+		case "245":
+			has245 = true
+		}
+		r, defined := rules[m.Field]
+		if first {
+			counts[m.Field]++
+			if defined && !r.repeatable && counts[m.Field] == 2 {
+				add(m.Field, m.Ord, "", Error, "non-repeatable field %s is repeated", m.Field)
+			}
+			if !defined && !isLocal(m.Field) {
+				add(m.Field, m.Ord, "", Info, "undefined tag %s", m.Field)
+			}
+		}
+		if m.Field < "010" {
+			continue
+		}
+		if first {
+			checkIndicator(add, m, 1, m.Ind1, r.ind1, defined)
+			checkIndicator(add, m, 2, m.Ind2, r.ind2, defined)
+		}
+		switch {
+		case !isSubfieldCode(m.SF):
+			add(m.Field, m.Ord, m.SF, Error, "invalid subfield code %q", m.SF)
+		case defined && r.subfields != "-" && !strings.Contains(r.subfields, m.SF):
+			add(m.Field, m.Ord, m.SF, Warning, "undefined subfield code %q for tag %s", m.SF, m.Field)
+		}
+	}
+	if !has008 {
+		add("008", 0, "", Error, "missing field 008")
+	}
+	if !has245 {
+		add("245", 0, "", Error, "missing field 245")
+	}
+	return findings
+}
+
+func checkIndicator(add func(string, int16, string, Severity, string, ...any), m srs.Marc, n int, ind, defined string, tagDefined bool) {
+	switch {
+	case len(ind) != 1 || !strings.Contains("0123456789abcdefghijklmnopqrstuvwxyz ", ind):
+		add(m.Field, m.Ord, "", Error, "invalid indicator %d value %q", n, ind)
+	case tagDefined && defined != "-" && !strings.Contains(defined, ind):
+		add(m.Field, m.Ord, "", Warning, "undefined indicator %d value %q for tag %s", n, ind, m.Field)
+	}
+}
+
+func isNumeric(tag string) bool {
+	if len(tag) != 3 {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		if tag[i] < '0' || tag[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isLocal reports whether tag is a locally defined field (9XX or X9X).
+func isLocal(tag string) bool {
+	return tag[0] == '9' || tag[1] == '9'
+}
+
+func isSubfieldCode(sf string) bool {
+	return len(sf) == 1 && ((sf[0] >= '0' && sf[0] <= '9') || (sf[0] >= 'a' && sf[0] <= 'z'))
+}
@@ -0,0 +1,92 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/library-data-platform/ldpmarc/marc/srs"
+)
+
+const leader = "00714cam a2200205 a 4500"
+
+// base returns the rows of a minimal valid record, with 008 and 245, to which
+// the rows of a test case are appended.
+func base(mrecs ...srs.Marc) []srs.Marc {
+	rows := []srs.Marc{
+		{Field: "000", Ord: 1, Content: leader},
+		{Field: "001", Ord: 1, Content: "in00000001"},
+		{Field: "008", Ord: 1, Content: "980101s1998    nyu           000 0 eng d"},
+		{Field: "245", Ord: 1, Ind1: "1", Ind2: "0", SF: "a", Content: "Title."},
+	}
+	return append(rows, mrecs...)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		leader string
+		mrecs  []srs.Marc
+		want   []Finding
+	}{
+		{"valid", leader, base(
+			srs.Marc{Field: "650", Ord: 1, Ind1: " ", Ind2: "0", SF: "a", Content: "Libraries"},
+			srs.Marc{Field: "650", Ord: 2, Ind1: " ", Ind2: "0", SF: "a", Content: "Archives"},
+			srs.Marc{Field: "999", Ord: 1, Ind1: "f", Ind2: "f", SF: "i", Content: "x"},
+		), nil},
+		{"no 001", leader, []srs.Marc{
+			{Field: "008", Ord: 1, Content: "980101s1998    nyu           000 0 eng d"},
+			{Field: "245", Ord: 1, Ind1: "1", Ind2: "0", SF: "a", Content: "Title."},
+		}, nil},
+		{"missing leader", "", base(), []Finding{
+			{Field: "000", Ord: 0, Severity: Error, Message: "missing leader"},
+		}},
+		{"short leader", "00714cam a2200205", base(), []Finding{
+			{Field: "000", Ord: 1, Severity: Error, Message: "leader length is 17, expected 24"},
+		}},
+		{"missing fields", leader, []srs.Marc{
+			{Field: "000", Ord: 1, Content: leader},
+			{Field: "001", Ord: 1, Content: "in00000001"},
+		}, []Finding{
+			{Field: "008", Ord: 0, Severity: Error, Message: "missing field 008"},
+			{Field: "245", Ord: 0, Severity: Error, Message: "missing field 245"},
+		}},
+		{"repeated", leader, base(
+			srs.Marc{Field: "245", Ord: 2, Ind1: "1", Ind2: "0", SF: "a", Content: "Other title."},
+			srs.Marc{Field: "245", Ord: 2, Ind1: "1", Ind2: "0", SF: "b", Content: "subtitle."},
+			srs.Marc{Field: "245", Ord: 3, Ind1: "1", Ind2: "0", SF: "a", Content: "Third title."},
+		), []Finding{
+			{Field: "245", Ord: 2, Severity: Error, Message: "non-repeatable field 245 is repeated"},
+		}},
+		{"indicators", leader, base(
+			srs.Marc{Field: "020", Ord: 1, Ind1: "1", Ind2: " ", SF: "a", Content: "0306406152"},
+			srs.Marc{Field: "650", Ord: 1, Ind1: " ", Ind2: "#", SF: "a", Content: "Libraries"},
+			srs.Marc{Field: "650", Ord: 1, Ind1: " ", Ind2: "#", SF: "x", Content: "Automation"},
+		), []Finding{
+			{Field: "020", Ord: 1, Severity: Warning, Message: "undefined indicator 1 value \"1\" for tag 020"},
+			{Field: "650", Ord: 1, Severity: Error, Message: "invalid indicator 2 value \"#\""},
+		}},
+		{"subfields", leader, base(
+			srs.Marc{Field: "020", Ord: 1, Ind1: " ", Ind2: " ", SF: "b", Content: "0306406152"},
+			srs.Marc{Field: "020", Ord: 1, Ind1: " ", Ind2: " ", SF: "A", Content: "(pbk.)"},
+			srs.Marc{Field: "950", Ord: 1, Ind1: " ", Ind2: " ", SF: "b", Content: "local"},
+		), []Finding{
+			{Field: "020", Ord: 1, SF: "b", Severity: Warning, Message: "undefined subfield code \"b\" for tag 020"},
+			{Field: "020", Ord: 1, SF: "A", Severity: Error, Message: "invalid subfield code \"A\""},
+		}},
+		{"tags", leader, base(
+			srs.Marc{Field: "01A", Ord: 1, Content: "x"},
+			srs.Marc{Field: "012", Ord: 1, Ind1: " ", Ind2: " ", SF: "a", Content: "x"},
+			srs.Marc{Field: "031", Ord: 1, Ind1: " ", Ind2: " ", SF: "a", Content: "1"},
+			srs.Marc{Field: "040", Ord: 1, Ind1: " ", Ind2: " ", SF: "a", Content: "DLC"},
+			srs.Marc{Field: "048", Ord: 1, Ind1: " ", Ind2: " ", SF: "a", Content: "ka01"},
+		), []Finding{
+			{Field: "01A", Ord: 1, Severity: Error, Message: "non-numeric tag \"01A\""},
+			{Field: "012", Ord: 1, Severity: Info, Message: "undefined tag 012"},
+		}},
+	}
+	for _, tt := range tests {
+		if got := Validate(tt.leader, tt.mrecs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Validate() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}